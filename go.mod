@@ -3,11 +3,14 @@ module kusionstack.io/component-base
 go 1.22.1
 
 require (
+	github.com/go-logr/logr v1.4.2
+	github.com/klauspost/compress v1.18.0
 	github.com/pkg/browser v0.0.0-20240102092130-5ac0b6a4141c
 	github.com/spf13/cobra v1.8.0
 	github.com/spf13/pflag v1.0.5
 	go.uber.org/zap v1.27.0
-	gopkg.in/natefinch/lumberjack.v2 v2.2.1
+	k8s.io/klog/v2 v2.130.1
+	sigs.k8s.io/yaml v1.4.0
 )
 
 require (
@@ -0,0 +1,236 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"bytes"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// OverflowBlock makes logging calls wait for room in a full async queue.
+	OverflowBlock = "block"
+	// OverflowDropOldest drops the oldest entry of a full async queue to make room.
+	OverflowDropOldest = "drop_oldest"
+	// OverflowDropNewest drops entries logged while the async queue is full.
+	OverflowDropNewest = "drop_newest"
+)
+
+var overflowListString = []string{OverflowBlock, OverflowDropOldest, OverflowDropNewest}
+
+// droppedEntries counts the entries dropped by every async sink.
+var droppedEntries atomic.Uint64
+
+// DroppedEntries returns the number of log entries dropped because an async queue was
+// full, since the process started.
+func DroppedEntries() uint64 {
+	return droppedEntries.Load()
+}
+
+// asyncEntry is a queued entry, along with its encoded form.
+type asyncEntry struct {
+	ent zapcore.Entry
+	p   []byte
+}
+
+// asyncSink should implement the EntrySink interface.
+var _ EntrySink = &asyncSink{}
+
+// asyncSink queues entries and writes them to the wrapped sink in the background,
+// buffered and flushed at regular intervals. Errors writing in the background are
+// reported to the error sink. Entries are written directly to the wrapped sink once the
+// async sink is closed.
+type asyncSink struct {
+	ws        zapcore.WriteSyncer
+	entrySink EntrySink
+	overflow  string
+	report    func(err error)
+
+	queue  chan asyncEntry
+	syncCh chan chan error
+	stop   chan struct{}
+	done   chan struct{}
+
+	// mu is held for reading while queueing entries, and for writing while closing.
+	mu     sync.RWMutex
+	closed bool
+}
+
+func newAsyncSink(ws zapcore.WriteSyncer, queueSize int, bufferSize Size, flushInterval time.Duration, overflow string, report func(err error)) *asyncSink {
+	s := &asyncSink{
+		ws:       ws,
+		overflow: overflow,
+		report:   report,
+		queue:    make(chan asyncEntry, queueSize),
+		syncCh:   make(chan chan error),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	s.entrySink, _ = ws.(EntrySink)
+
+	go s.run(int(bufferSize), flushInterval)
+	return s
+}
+
+// run writes queued entries until the sink is closed. Whole entries are buffered up to
+// bufferSize bytes, if not zero, so that an entry is never split across writes. Entries
+// are written to entry sinks directly.
+func (s *asyncSink) run(bufferSize int, flushInterval time.Duration) {
+	defer close(s.done)
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	var buf bytes.Buffer
+	flush := func() {
+		if buf.Len() == 0 {
+			return
+		}
+		// the buffer is dropped on errors, rather than written again with later entries
+		_, err := s.ws.Write(buf.Bytes())
+		buf.Reset()
+		if err != nil {
+			s.report(err)
+		}
+	}
+	write := func(e asyncEntry) {
+		var err error
+		switch {
+		case s.entrySink != nil:
+			err = s.entrySink.WriteEntry(e.ent, e.p)
+		case buf.Len()+len(e.p) <= bufferSize:
+			buf.Write(e.p)
+		default:
+			// make room for the entry, and write it directly if it doesn't fit
+			flush()
+			if len(e.p) <= bufferSize {
+				buf.Write(e.p)
+				return
+			}
+			_, err = s.ws.Write(e.p)
+		}
+		if err != nil {
+			s.report(err)
+		}
+	}
+	drain := func() {
+		for {
+			select {
+			case e := <-s.queue:
+				write(e)
+			default:
+				flush()
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case e := <-s.queue:
+			write(e)
+		case <-ticker.C:
+			flush()
+		case reply := <-s.syncCh:
+			drain()
+			reply <- s.ws.Sync()
+		case <-s.stop:
+			drain()
+			return
+		}
+	}
+}
+
+// WriteEntry queues the entry according to the overflow policy.
+func (s *asyncSink) WriteEntry(ent zapcore.Entry, p []byte) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		if s.entrySink != nil {
+			return s.entrySink.WriteEntry(ent, p)
+		}
+		_, err := s.ws.Write(p)
+		return err
+	}
+
+	// the encoded entry is reused once written
+	e := asyncEntry{ent: ent, p: append([]byte(nil), p...)}
+	switch s.overflow {
+	case OverflowDropNewest:
+		select {
+		case s.queue <- e:
+		default:
+			droppedEntries.Add(1)
+		}
+	case OverflowDropOldest:
+		for {
+			select {
+			case s.queue <- e:
+				return nil
+			default:
+			}
+			select {
+			case <-s.queue:
+				droppedEntries.Add(1)
+			default:
+			}
+		}
+	default:
+		s.queue <- e
+	}
+	return nil
+}
+
+// Write queues p as an info entry.
+func (s *asyncSink) Write(p []byte) (int, error) {
+	if err := s.WriteEntry(zapcore.Entry{Level: zapcore.InfoLevel, Time: time.Now()}, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Sync writes every queued entry and syncs the wrapped sink.
+func (s *asyncSink) Sync() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return s.ws.Sync()
+	}
+
+	reply := make(chan error)
+	s.syncCh <- reply
+	return <-reply
+}
+
+// Close writes every queued entry and stops writing in the background. The wrapped
+// sink isn't closed.
+func (s *asyncSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	close(s.stop)
+	<-s.done
+	s.closed = true
+	return s.ws.Sync()
+}
@@ -0,0 +1,268 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// gatedWriter blocks its first write until released.
+type gatedWriter struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	started chan struct{}
+	release chan struct{}
+	once    sync.Once
+}
+
+func newGatedWriter() *gatedWriter {
+	return &gatedWriter{started: make(chan struct{}), release: make(chan struct{})}
+}
+
+func (w *gatedWriter) Write(p []byte) (int, error) {
+	w.once.Do(func() {
+		close(w.started)
+		<-w.release
+	})
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *gatedWriter) Sync() error { return nil }
+
+func (w *gatedWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+// failOnError returns an error reporter failing the test.
+func failOnError(t *testing.T) func(error) {
+	return func(err error) {
+		t.Errorf("Got error '%v', expected success", err)
+	}
+}
+
+func TestAsyncSinkOverflow(t *testing.T) {
+	cases := []struct {
+		overflow string
+		expected string
+		dropped  uint64
+	}{
+		{OverflowBlock, "12345", 0},
+		{OverflowDropNewest, "123", 2},
+		{OverflowDropOldest, "145", 2},
+	}
+
+	for _, c := range cases {
+		t.Run(c.overflow, func(t *testing.T) {
+			w := newGatedWriter()
+			s := newAsyncSink(w, 2, 0, time.Hour, c.overflow, failOnError(t))
+			dropped := DroppedEntries()
+
+			// the first entry is taken off the queue and blocks the writer
+			_, _ = s.Write([]byte("1"))
+			<-w.started
+
+			// the queue holds two entries
+			_, _ = s.Write([]byte("2"))
+			_, _ = s.Write([]byte("3"))
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				_, _ = s.Write([]byte("4"))
+				_, _ = s.Write([]byte("5"))
+			}()
+
+			if c.overflow == OverflowBlock {
+				select {
+				case <-done:
+					t.Fatal("Got writes to a full queue returning, expected them to block")
+				case <-time.After(50 * time.Millisecond):
+				}
+			} else {
+				<-done
+			}
+
+			close(w.release)
+			<-done
+			if err := s.Sync(); err != nil {
+				t.Errorf("Got error '%v', expected success", err)
+			}
+
+			if got := w.String(); got != c.expected {
+				t.Errorf("Got %q, expected %q", got, c.expected)
+			}
+			if got := DroppedEntries() - dropped; got != c.dropped {
+				t.Errorf("Got %d dropped entries, expected %d", got, c.dropped)
+			}
+			_ = s.Close()
+		})
+	}
+}
+
+func TestAsyncSinkClose(t *testing.T) {
+	w := newGatedWriter()
+	close(w.release)
+	s := newAsyncSink(w, 10, 1024, time.Hour, OverflowBlock, failOnError(t))
+
+	_, _ = s.Write([]byte("buffered\n"))
+	if err := s.Close(); err != nil {
+		t.Errorf("Got error '%v', expected success", err)
+	}
+
+	// once closed, entries are written directly
+	_, _ = s.Write([]byte("direct\n"))
+	if got := w.String(); got != "buffered\ndirect\n" {
+		t.Errorf("Got %q, expected both entries", got)
+	}
+}
+
+func TestConfigureAsync(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "async.log")
+
+	o := testOptions()
+	o.OutputPath = path
+	o.Async = true
+	o.AsyncFlushInterval = "1h"
+	if err := Configure(o); err != nil {
+		t.Fatalf("Got err '%v', expecting success", err)
+	}
+
+	Info("first")
+	content, _ := os.ReadFile(path)
+	if len(content) != 0 {
+		t.Errorf("Got %q, expected entries to be buffered", content)
+	}
+
+	// syncing flushes the buffer
+	_ = Sync()
+	content, _ = os.ReadFile(path)
+	if !strings.HasSuffix(string(content), "\tinfo\tfirst\n") {
+		t.Errorf("Got %q, expected the entry to be flushed", content)
+	}
+
+	// configuring again flushes the previous outputs
+	Info("second")
+	_ = Configure(testOptions())
+	content, _ = os.ReadFile(path)
+	if !strings.HasSuffix(string(content), "\tinfo\tsecond\n") {
+		t.Errorf("Got %q, expected the entry to be flushed", content)
+	}
+}
+
+func TestAsyncFlushInterval(t *testing.T) {
+	w := newGatedWriter()
+	close(w.release)
+	s := newAsyncSink(w, 10, 1024, 10*time.Millisecond, OverflowBlock, failOnError(t))
+	defer s.Close()
+
+	_, _ = s.Write([]byte("flushed\n"))
+	deadline := time.Now().Add(5 * time.Second)
+	for w.String() == "" && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := w.String(); got != "flushed\n" {
+		t.Errorf("Got %q, expected the buffer to be flushed in the background", got)
+	}
+}
+
+// recordingWriter records each write, failing the writes while fail is set.
+type recordingWriter struct {
+	mu     sync.Mutex
+	writes []string
+	fail   bool
+}
+
+func (w *recordingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.fail {
+		return 0, errors.New("disk full")
+	}
+	w.writes = append(w.writes, string(p))
+	return len(p), nil
+}
+
+func (w *recordingWriter) Sync() error { return nil }
+
+func (w *recordingWriter) setFail(fail bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.fail = fail
+}
+
+func TestAsyncSinkWholeEntries(t *testing.T) {
+	w := &recordingWriter{}
+	s := newAsyncSink(w, 10, 10, time.Hour, OverflowBlock, failOnError(t))
+
+	for _, p := range []string{"first\n", "second\n", "a\n", "b\n", "too long entry\n"} {
+		_, _ = s.Write([]byte(p))
+	}
+	_ = s.Close()
+
+	expected := []string{"first\n", "second\na\n", "b\n", "too long entry\n"}
+	if !reflect.DeepEqual(w.writes, expected) {
+		t.Errorf("Got writes %q, expected %q", w.writes, expected)
+	}
+}
+
+func TestAsyncSinkWriteErrors(t *testing.T) {
+	var handled []error
+	SetWriteErrorHandler(func(err error) { handled = append(handled, err) })
+	defer SetWriteErrorHandler(nil)
+
+	var errOutput bytes.Buffer
+	w := &recordingWriter{fail: true}
+	s := newAsyncSink(w, 10, 1024, time.Hour, OverflowBlock, (&errorSink{ws: zapcore.AddSync(&errOutput)}).report)
+	count := WriteErrors()
+
+	_, _ = s.Write([]byte("lost\n"))
+	if err := s.Sync(); err != nil {
+		t.Errorf("Got error '%v', expected the write error to be reported instead", err)
+	}
+
+	// the failed entry is dropped, rather than written along with the next ones
+	w.setFail(false)
+	_, _ = s.Write([]byte("written\n"))
+	_ = s.Close()
+
+	if got := WriteErrors() - count; got != 1 {
+		t.Errorf("Got %d write errors, expected 1", got)
+	}
+	if len(handled) != 1 || handled[0].Error() != "disk full" {
+		t.Errorf("Got %v, expected the handler to be called with the write error", handled)
+	}
+	if !strings.HasSuffix(errOutput.String(), "log write error: disk full\n") {
+		t.Errorf("Got %q, expected the write error in the error output", errOutput.String())
+	}
+	if !reflect.DeepEqual(w.writes, []string{"written\n"}) {
+		t.Errorf("Got writes %q, expected the entry written after the error", w.writes)
+	}
+}
@@ -15,14 +15,15 @@
 package log
 
 import (
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
 	"sync/atomic"
 	"time"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
-	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 // The default encoder config
@@ -45,65 +46,114 @@ type functionTable struct {
 	write       func(ent zapcore.Entry, fields []zapcore.Field) error
 	sync        func() error
 	exitProcess func(code int)
-	errorSink   zapcore.WriteSyncer
+	errorSink   *errorSink
 	close       func() error
+	options     Options
+	// slogDefault is the log/slog default logger replaced when capturing slog output
+	slogDefault *slog.Logger
 }
 
 // functions that can be replaced by tests
 var funcs = &atomic.Value{}
 
 func init() {
-	// use our defaults for starters so that logging works even before everything is fully configured
-	_ = Configure(DefaultOptions())
+	// use our defaults for starters so that logging works even before everything is fully configured,
+	// leaving the environment and config file to the application's own Configure call
+	o := DefaultOptions()
+	o.completed = true
+	_ = Configure(o)
 }
 
-// prepZap sets up the core Zap loggers
-func prepZap(options *Options) (zapcore.Core, func() zapcore.Core, zapcore.WriteSyncer, error) {
-	var enc zapcore.Encoder
-	encCfg := defaultEncoderConfig
-
-	if options.JSONEncoding {
-		enc = zapcore.NewJSONEncoder(encCfg)
-	} else {
-		enc = zapcore.NewConsoleEncoder(encCfg)
-	}
+// prepZap sets up the core Zap loggers. The returned function stops writing to the outputs
+// in the background, if the options enable it, then closes the outputs and the error output.
+func prepZap(options *Options) (zapcore.Core, func() zapcore.Core, *errorSink, func() error, error) {
+	// closers close the outputs, once the async sinks writing to them are closed
+	var closers []func() error
 
 	var rotaterSink zapcore.WriteSyncer
 	if options.RotateOutputPath != "" {
-		rotaterSink = zapcore.AddSync(&lumberjack.Logger{
-			Filename:   options.RotateOutputPath,
-			MaxSize:    options.RotationMaxSize,
-			MaxBackups: options.RotationMaxBackups,
-			MaxAge:     options.RotationMaxAge,
-		})
+		rs, err := newRotatingSink(options.RotateOutputPath, options)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+		rotaterSink = rs
+		closers = append(closers, rs.Close)
 	}
 
-	errSink, closeErrorSink, err := zap.Open(options.OutputPath)
+	errorOutputPath := options.ErrorOutputPath
+	if errorOutputPath == "" {
+		errorOutputPath = DefaultErrorOutputPath
+	}
+	errSink, closeErrorSink, err := openSink(errorOutputPath)
 	if err != nil {
-		return nil, nil, nil, err
+		_ = closeAll(closers)
+		return nil, nil, nil, nil, err
 	}
+	closers = append([]func() error{func() error {
+		closeErrorSink()
+		return nil
+	}}, closers...)
+	reporter := &errorSink{ws: errSink}
 
 	var outputSink zapcore.WriteSyncer
 	if len(options.OutputPath) > 0 {
-		outputSink, _, err = zap.Open(options.OutputPath)
+		var closeOutputSink func()
+		outputSink, closeOutputSink, err = openSink(options.OutputPath)
 		if err != nil {
-			closeErrorSink()
-			return nil, nil, nil, err
+			_ = closeAll(closers)
+			return nil, nil, nil, nil, err
+		}
+		closers = append(closers, func() error {
+			closeOutputSink()
+			return nil
+		})
+	}
+
+	var asyncSinks []*asyncSink
+	wrap := func(ws zapcore.WriteSyncer) zapcore.WriteSyncer {
+		if !options.Async {
+			return ws
+		}
+		flushInterval, _ := time.ParseDuration(options.AsyncFlushInterval)
+		s := newAsyncSink(ws, options.AsyncQueueSize, options.AsyncBufferSize, flushInterval, options.AsyncOverflow, reporter.report)
+		asyncSinks = append(asyncSinks, s)
+		return s
+	}
+	closeSinks := func() error {
+		var errs []error
+		for _, s := range asyncSinks {
+			errs = append(errs, s.Close())
 		}
+		errs = append(errs, closeAll(closers))
+		return errors.Join(errs...)
+	}
+
+	var sinks []zapcore.WriteSyncer
+	var encoders []zapcore.Encoder
+	if outputSink != nil {
+		sinks = append(sinks, wrap(outputSink))
+		encoders = append(encoders, newEncoder(options.encoding(), options, options.OutputPath))
+	}
+	if rotaterSink != nil {
+		sinks = append(sinks, wrap(rotaterSink))
+		encoders = append(encoders, newEncoder(options.encoding(), options, options.RotateOutputPath))
+	}
+
+	sinkCores, sinkClosers, err := openSinks(options, wrap)
+	if err != nil {
+		_ = closeSinks()
+		return nil, nil, nil, nil, err
 	}
+	closers = append(closers, sinkClosers...)
 
-	var sink zapcore.WriteSyncer
-	if rotaterSink != nil && outputSink != nil {
-		sink = zapcore.NewMultiWriteSyncer(outputSink, rotaterSink)
-	} else if rotaterSink != nil {
-		sink = rotaterSink
-	} else {
-		sink = outputSink
+	alwaysOnCores := make([]zapcore.Core, 0, len(sinks)+len(sinkCores))
+	for i, sink := range sinks {
+		alwaysOnCores = append(alwaysOnCores, newCore(encoders[i], sink, zap.NewAtomicLevelAt(zapcore.DebugLevel)))
 	}
+	alwaysOn := zapcore.NewTee(append(alwaysOnCores, sinkCores...)...)
 
-	alwaysOn := zapcore.NewCore(enc, sink, zap.NewAtomicLevelAt(zapcore.DebugLevel))
 	conditionallyOn := func() zapcore.Core {
-		enabler := func(lvl zapcore.Level) bool {
+		enabler := zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
 			switch lvl {
 			case zapcore.ErrorLevel:
 				return defaultLogger.ErrorEnabled()
@@ -113,22 +163,43 @@ func prepZap(options *Options) (zapcore.Core, func() zapcore.Core, zapcore.Write
 				return defaultLogger.InfoEnabled()
 			}
 			return defaultLogger.DebugEnabled()
+		})
+
+		cores := make([]zapcore.Core, 0, len(sinks)+len(sinkCores))
+		for i, sink := range sinks {
+			cores = append(cores, newCore(encoders[i], sink, enabler))
 		}
-		return zapcore.NewCore(enc, sink, zap.LevelEnablerFunc(enabler))
+		for _, c := range sinkCores {
+			cores = append(cores, &leveledCore{Core: c, enabler: enabler})
+		}
+		return zapcore.NewTee(cores...)
 	}
-	return alwaysOn, conditionallyOn, errSink, nil
+	return alwaysOn, conditionallyOn, reporter, closeSinks, nil
 }
 
 // Configure initializes a functional logging subsystem.
 //
 // You typically call this once at process startup.
 // Once this call returns, the logging system is ready to accept data.
+// The options are completed first, applying the environment variables and ConfigFile,
+// unless Complete was already called.
 func Configure(opts *Options) error {
+	// apply the environment and the config file if the options weren't completed
+	if !opts.completed {
+		if err := opts.Complete(opts.flags); err != nil {
+			return err
+		}
+	}
+
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
 	if err := updateLogger(opts); err != nil {
 		return err
 	}
 
-	baseLogger, logBuilder, errSink, err := prepZap(opts)
+	baseLogger, logBuilder, errSink, closeSinks, err := prepZap(opts)
 	if err != nil {
 		return err
 	}
@@ -149,11 +220,28 @@ func Configure(opts *Options) error {
 		close: func() error {
 			// best-effort to sync
 			_ = baseLogger.Sync()
-			return nil
+			return closeSinks()
 		},
+		options: *opts,
+	}
+	prev, _ := funcs.Load().(functionTable)
+	restoreSlog := false
+	if opts.CaptureSlog {
+		ft.slogDefault = prev.slogDefault
+		if ft.slogDefault == nil {
+			ft.slogDefault = slog.Default()
+		}
+	} else if prev.slogDefault != nil {
+		// only restore the previous default if our handler wasn't replaced in the meantime
+		_, restoreSlog = slog.Default().Handler().(*slogHandler)
 	}
 	funcs.Store(ft)
 
+	// write out the entries still queued for the previous outputs and close the previous error output
+	if prev.close != nil {
+		_ = prev.close()
+	}
+
 	zapOptions := []zap.Option{
 		zap.ErrorOutput(errSink),
 		zap.AddCallerSkip(1),
@@ -173,35 +261,53 @@ func Configure(opts *Options) error {
 	// capture global zap logging and force it through our logger
 	_ = zap.ReplaceGlobals(defaultZapLogger)
 
+	// capture log/slog output and force it through our logger. This must happen before
+	// redirecting the "log" package, since slog.SetDefault redirects it as well.
+	// Once slog output is no longer captured, the default from before is restored.
+	if opts.CaptureSlog {
+		slog.SetDefault(slog.New(NewSlogHandler(defaultScope)))
+	} else if restoreSlog {
+		slog.SetDefault(prev.slogDefault)
+	}
+
 	// capture standard golang "log" package output and force it through our logger
 	_ = zap.RedirectStdLog(defaultZapLogger)
 
 	return nil
 }
 
+// updateLogger applies the levels, caller setting and sampling from the options to every registered scope.
 func updateLogger(opts *Options) error {
-	if defaultLogger == nil {
-		defaultLogger = &logger{
-			callerSkip: 1,
+	outputLevels, err := parseScopedLevels(opts.OutputLevel, DefaultOutputLevel)
+	if err != nil {
+		return fmt.Errorf("invalid output level: %v", err)
+	}
+
+	var stackTraceLevels *scopedLevels
+	if len(opts.StackTraceLevel) != 0 {
+		stackTraceLevels, err = parseScopedLevels(opts.StackTraceLevel, DefaultStackTraceLevel)
+		if err != nil {
+			return fmt.Errorf("invalid stack trace level: %v", err)
 		}
 	}
 
-	level, ok := stringToLevel[opts.OutputLevel]
-	if !ok {
-		return fmt.Errorf("invalid output level '%s'", opts.OutputLevel)
+	sampling, err := opts.sampling()
+	if err != nil {
+		return err
 	}
-	defaultLogger.SetOutputLevel(level)
 
-	if len(opts.StackTraceLevel) != 0 {
-		stackTraceLevel, ok := stringToLevel[opts.StackTraceLevel]
-		if !ok {
-			return fmt.Errorf("invalid stack trace level '%s'", opts.OutputLevel)
+	// scopes registered later, such as by logr, get the settings listed for them too
+	configuredScopes.Store(&scopeSettings{output: outputLevels, stackTrace: stackTraceLevels, sampling: sampling})
+
+	for name, s := range Scopes() {
+		s.SetOutputLevel(outputLevels.levelFor(name))
+		s.SetSampling(sampling.samplingFor(name))
+		if stackTraceLevels != nil {
+			s.SetStackTraceLevel(stackTraceLevels.levelFor(name))
 		}
-		defaultLogger.SetStackTraceLevel(stackTraceLevel)
+		s.SetLogCallers(opts.LogCaller)
 	}
 
-	defaultLogger.SetLogCallers(opts.LogCaller)
-
 	return nil
 }
 
@@ -254,3 +360,11 @@ func Sync() error {
 func Close() error {
 	return funcs.Load().(functionTable).close()
 }
+
+// CurrentOptions returns a copy of the options the logger was last configured with,
+// such as to configure it again with them once done with other options.
+func CurrentOptions() *Options {
+	o := funcs.Load().(functionTable).options
+	o.Sinks = append([]SinkOptions(nil), o.Sinks...)
+	return &o
+}
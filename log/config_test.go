@@ -147,3 +147,24 @@ func TestCapture(t *testing.T) {
 
 	t.Error("Could not find stack trace info in output")
 }
+
+func TestCurrentOptions(t *testing.T) {
+	o := testOptions()
+	o.OutputLevel = "debug"
+	o.Sinks = []SinkOptions{{Path: "stderr"}}
+	if err := Configure(o); err != nil {
+		t.Fatalf("Got err '%v', expecting success", err)
+	}
+	defer func() { _ = Configure(testOptions()) }()
+
+	current := CurrentOptions()
+	if current.OutputLevel != "debug" || len(current.Sinks) != 1 {
+		t.Errorf("Got %+v, expecting the configured options", current)
+	}
+
+	// the copy can be changed without affecting the next one
+	current.Sinks[0].Path = "stdout"
+	if got := CurrentOptions().Sinks[0].Path; got != "stderr" {
+		t.Errorf("Got sink path '%s', expecting 'stderr'", got)
+	}
+}
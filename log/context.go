@@ -0,0 +1,201 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"context"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// TraceIDField is the field under which the trace ID stored with WithTraceID is logged.
+	TraceIDField = "trace_id"
+	// SpanIDField is the field under which the span ID stored with WithSpanID is logged.
+	SpanIDField = "span_id"
+)
+
+type (
+	scopeContextKey   struct{}
+	traceIDContextKey struct{}
+	spanIDContextKey  struct{}
+)
+
+// contextField maps a context key to the field its value is logged under.
+type contextField struct {
+	key   any
+	field string
+}
+
+var (
+	contextFields = []contextField{
+		{key: traceIDContextKey{}, field: TraceIDField},
+		{key: spanIDContextKey{}, field: SpanIDField},
+	}
+	contextFieldsLock sync.RWMutex
+)
+
+// contextDefaultScope is the default scope as seen by callers using it directly rather
+// than through the package-level functions.
+var contextDefaultScope = defaultScope.With()
+
+// IntoContext returns a copy of ctx carrying the given scope, which may be a child
+// created with With. The scope can be retrieved with FromContext.
+func IntoContext(ctx context.Context, s *Scope) context.Context {
+	return context.WithValue(ctx, scopeContextKey{}, s)
+}
+
+// FromContext returns the scope stored in ctx by IntoContext, or the default scope if
+// there is none.
+func FromContext(ctx context.Context) *Scope {
+	if s, ok := ctx.Value(scopeContextKey{}).(*Scope); ok && s != nil {
+		return s
+	}
+	return contextDefaultScope
+}
+
+// WithTraceID returns a copy of ctx carrying the trace ID, which the Ctx logging
+// functions attach to every entry as the trace_id field.
+func WithTraceID(ctx context.Context, traceID string) context.Context {
+	return context.WithValue(ctx, traceIDContextKey{}, traceID)
+}
+
+// WithSpanID returns a copy of ctx carrying the span ID, which the Ctx logging
+// functions attach to every entry as the span_id field.
+func WithSpanID(ctx context.Context, spanID string) context.Context {
+	return context.WithValue(ctx, spanIDContextKey{}, spanID)
+}
+
+// RegisterContextKey registers a context key whose value, when present, the Ctx logging
+// functions attach to every entry as the named field. Registering a key again changes
+// its field name.
+func RegisterContextKey(key any, field string) {
+	contextFieldsLock.Lock()
+	defer contextFieldsLock.Unlock()
+
+	for i := range contextFields {
+		if contextFields[i].key == key {
+			contextFields[i].field = field
+			return
+		}
+	}
+	contextFields = append(contextFields, contextField{key: key, field: field})
+}
+
+// fieldsFromContext returns the registered context values found in ctx as zap fields,
+// followed by the given key/value pairs.
+func fieldsFromContext(ctx context.Context, keysAndValues []any) []zapcore.Field {
+	contextFieldsLock.RLock()
+	defer contextFieldsLock.RUnlock()
+
+	var fields []zapcore.Field
+	for _, cf := range contextFields {
+		if v := ctx.Value(cf.key); v != nil {
+			fields = append(fields, zap.Any(cf.field, v))
+		}
+	}
+
+	return append(fields, toFields(keysAndValues)...)
+}
+
+// contextOutput outputs a message at the level through the scope stored in ctx, if the
+// scope enables the level. It is called by the package-level functions, whose callers
+// are reported.
+func contextOutput(ctx context.Context, level Level, msg string, fields func() []zapcore.Field) {
+	l := FromContext(ctx).logger
+	if l.GetOutputLevel() >= level {
+		l.outputSkip(1, levelToZap[level], msg, fields())
+	}
+}
+
+// DebugCtx outputs a message at debug level with the context values and the given key/value
+// pairs attached as structured fields.
+func (l *logger) DebugCtx(ctx context.Context, msg string, keysAndValues ...any) {
+	if l.GetOutputLevel() >= DebugLevel {
+		l.outputSkip(l.callerSkip, zapcore.DebugLevel, msg, fieldsFromContext(ctx, keysAndValues))
+	}
+}
+
+// InfoCtx outputs a message at info level with the context values and the given key/value
+// pairs attached as structured fields.
+func (l *logger) InfoCtx(ctx context.Context, msg string, keysAndValues ...any) {
+	if l.GetOutputLevel() >= InfoLevel {
+		l.outputSkip(l.callerSkip, zapcore.InfoLevel, msg, fieldsFromContext(ctx, keysAndValues))
+	}
+}
+
+// WarnCtx outputs a message at warn level with the context values and the given key/value
+// pairs attached as structured fields.
+func (l *logger) WarnCtx(ctx context.Context, msg string, keysAndValues ...any) {
+	if l.GetOutputLevel() >= WarnLevel {
+		l.outputSkip(l.callerSkip, zapcore.WarnLevel, msg, fieldsFromContext(ctx, keysAndValues))
+	}
+}
+
+// ErrorCtx outputs a message at error level with the error, the context values and the given
+// key/value pairs attached as structured fields. The error is omitted if it is nil.
+func (l *logger) ErrorCtx(ctx context.Context, err error, msg string, keysAndValues ...any) {
+	if l.GetOutputLevel() >= ErrorLevel {
+		l.outputSkip(l.callerSkip, zapcore.ErrorLevel, msg, contextErrorFields(ctx, err, keysAndValues))
+	}
+}
+
+// FatalCtx outputs a message at fatal level with the error, the context values and the given
+// key/value pairs attached as structured fields. The error is omitted if it is nil.
+func (l *logger) FatalCtx(ctx context.Context, err error, msg string, keysAndValues ...any) {
+	if l.GetOutputLevel() >= FatalLevel {
+		l.outputSkip(l.callerSkip, zapcore.FatalLevel, msg, contextErrorFields(ctx, err, keysAndValues))
+	}
+}
+
+// contextErrorFields is like fieldsFromContext, with the error first if it isn't nil.
+func contextErrorFields(ctx context.Context, err error, keysAndValues []any) []zapcore.Field {
+	if err == nil {
+		return fieldsFromContext(ctx, keysAndValues)
+	}
+	return append([]zapcore.Field{zap.Error(err)}, fieldsFromContext(ctx, keysAndValues)...)
+}
+
+// DebugCtx logs to the DEBUG log through the scope stored in ctx, with the context values
+// and the given key/value pairs attached as structured fields.
+func DebugCtx(ctx context.Context, msg string, keysAndValues ...any) {
+	contextOutput(ctx, DebugLevel, msg, func() []zapcore.Field { return fieldsFromContext(ctx, keysAndValues) })
+}
+
+// InfoCtx logs to the INFO log through the scope stored in ctx, with the context values
+// and the given key/value pairs attached as structured fields.
+func InfoCtx(ctx context.Context, msg string, keysAndValues ...any) {
+	contextOutput(ctx, InfoLevel, msg, func() []zapcore.Field { return fieldsFromContext(ctx, keysAndValues) })
+}
+
+// WarnCtx logs to the WARN log through the scope stored in ctx, with the context values
+// and the given key/value pairs attached as structured fields.
+func WarnCtx(ctx context.Context, msg string, keysAndValues ...any) {
+	contextOutput(ctx, WarnLevel, msg, func() []zapcore.Field { return fieldsFromContext(ctx, keysAndValues) })
+}
+
+// ErrorCtx logs to the ERROR log through the scope stored in ctx, with the error, the context
+// values and the given key/value pairs attached as structured fields.
+func ErrorCtx(ctx context.Context, err error, msg string, keysAndValues ...any) {
+	contextOutput(ctx, ErrorLevel, msg, func() []zapcore.Field { return contextErrorFields(ctx, err, keysAndValues) })
+}
+
+// FatalCtx logs to the FATAL log through the scope stored in ctx, with the error, the context
+// values and the given key/value pairs attached as structured fields.
+func FatalCtx(ctx context.Context, err error, msg string, keysAndValues ...any) {
+	contextOutput(ctx, FatalLevel, msg, func() []zapcore.Field { return contextErrorFields(ctx, err, keysAndValues) })
+}
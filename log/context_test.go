@@ -0,0 +1,118 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type tenantKey struct{}
+
+func TestFromContext(t *testing.T) {
+	if s := FromContext(context.Background()); s.Name() != DefaultLoggerName {
+		t.Errorf("Got scope %s, expecting the default scope", s.Name())
+	}
+
+	s := RegisterScope("ctx", "").With("request", "r1")
+	ctx := IntoContext(context.Background(), s)
+	if FromContext(ctx) != s {
+		t.Error("Expecting FromContext to return the scope stored by IntoContext")
+	}
+}
+
+func TestContextFields(t *testing.T) {
+	RegisterContextKey(tenantKey{}, "tenant")
+
+	s := RegisterScope("ctx", "")
+	ctx := IntoContext(context.Background(), s.With("request", "r1"))
+	ctx = WithTraceID(ctx, "t1")
+	ctx = WithSpanID(ctx, "s1")
+	ctx = context.WithValue(ctx, tenantKey{}, "acme")
+
+	lines, err := captureStdout(func() {
+		o := testOptions()
+		o.JSONEncoding = true
+		o.OutputLevel = "debug"
+		if err := Configure(o); err != nil {
+			t.Errorf("Got err '%v', expecting success", err)
+		}
+
+		InfoCtx(ctx, "package", "replicas", 3)
+		s.DebugCtx(ctx, "method")
+		WarnCtx(context.Background(), "plain")
+		ErrorCtx(WithTraceID(context.Background(), "t2"), errors.New("boom"), "error")
+		_ = Sync()
+	})
+	if err != nil {
+		t.Fatalf("Got error '%v', expected success", err)
+	}
+
+	patterns := []string{
+		`"scope":"ctx","msg":"package","request":"r1","trace_id":"t1","span_id":"s1","tenant":"acme","replicas":3}`,
+		`"scope":"ctx","msg":"method","trace_id":"t1","span_id":"s1","tenant":"acme"}`,
+		`"level":"warn",.*"msg":"plain"}`,
+		`"level":"error",.*"msg":"error","error":"boom","trace_id":"t2"}`,
+		"",
+	}
+	if len(lines) != len(patterns) {
+		t.Fatalf("Got %d lines of output %v, expecting %d", len(lines), lines, len(patterns))
+	}
+	for i, pat := range patterns {
+		if match, _ := regexp.MatchString(pat+"$", lines[i]); !match {
+			t.Errorf("Got '%s', expecting to match '%s'", lines[i], pat)
+		}
+	}
+}
+
+func TestContextCaller(t *testing.T) {
+	ctx := IntoContext(context.Background(), RegisterScope("ctx", ""))
+
+	var line int
+	lines, _ := captureStdout(func() {
+		o := testOptions()
+		o.LogCaller = true
+		_ = Configure(o)
+
+		_, _, line, _ = runtime.Caller(0)
+		InfoCtx(ctx, "scoped")
+		InfoCtx(context.Background(), "unscoped")
+		FromContext(context.Background()).Info("direct")
+		_ = Sync()
+	})
+
+	for i, msg := range []string{"scoped", "unscoped", "direct"} {
+		want := "log/context_test.go:" + strconv.Itoa(line+1+i) + "\t" + msg
+		if !strings.Contains(lines[i], want) {
+			t.Errorf("Got '%s', expecting it to contain '%s'", lines[i], want)
+		}
+	}
+}
+
+func TestContextDisabledAllocs(t *testing.T) {
+	if err := Configure(testOptions()); err != nil {
+		t.Fatalf("Got err '%v', expecting success", err)
+	}
+
+	ctx := WithTraceID(context.Background(), "t1")
+	if allocs := testing.AllocsPerRun(100, func() { DebugCtx(ctx, "disabled") }); allocs != 0 {
+		t.Errorf("Got %v allocations logging at a disabled level, expecting none", allocs)
+	}
+}
@@ -0,0 +1,30 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package log provides scoped, structured logging on top of Zap, configured by Options
+// from flags, environment variables and configuration files:
+//
+//	o := log.DefaultOptions()
+//	o.AddFlags(cmd.Flags())
+//
+//	// once the flags are parsed
+//	if err := log.Configure(o); err != nil {
+//		return err
+//	}
+//
+// Sizes are Size values in bytes. Options.RotationMaxSizeBytes replaces the former
+// RotationMaxSize field, a number of megabytes, so that code setting the old field fails
+// to compile rather than rotating files every few bytes. In flags and configuration files,
+// sizes are given with a unit, such as 100Mi.
+package log
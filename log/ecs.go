@@ -0,0 +1,98 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// ecsVersion is the version of the Elastic Common Schema entries conform to.
+const ecsVersion = "1.6.0"
+
+// ecsEncoderConfig maps the entries to their Elastic Common Schema fields.
+var ecsEncoderConfig = zapcore.EncoderConfig{
+	TimeKey:        "@timestamp",
+	LevelKey:       "log.level",
+	NameKey:        "log.logger",
+	MessageKey:     "message",
+	StacktraceKey:  "error.stack_trace",
+	LineEnding:     zapcore.DefaultLineEnding,
+	EncodeLevel:    zapcore.LowercaseLevelEncoder,
+	EncodeDuration: zapcore.NanosDurationEncoder,
+	EncodeTime:     formatDate,
+}
+
+// ecsEncoder formats entries as JSON following the Elastic Common Schema, so that they
+// can be shipped to Elasticsearch as they are. The caller is written as log.origin,
+// and the error, trace_id and span_id fields as error.message, trace.id and span.id.
+// Other fields are written as they are.
+type ecsEncoder struct {
+	zapcore.Encoder
+}
+
+func newECSEncoder() zapcore.Encoder {
+	return &ecsEncoder{Encoder: zapcore.NewJSONEncoder(ecsEncoderConfig)}
+}
+
+func (e *ecsEncoder) Clone() zapcore.Encoder {
+	return &ecsEncoder{Encoder: e.Encoder.Clone()}
+}
+
+// AddString writes the trace_id and span_id context fields as trace.id and span.id.
+func (e *ecsEncoder) AddString(key, value string) {
+	switch key {
+	case TraceIDField:
+		key = "trace.id"
+	case SpanIDField:
+		key = "span.id"
+	}
+	e.Encoder.AddString(key, value)
+}
+
+func (e *ecsEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	ecsFields := make([]zapcore.Field, 0, len(fields)+2)
+	if ent.Caller.Defined {
+		ecsFields = append(ecsFields, zap.Object("log.origin", ecsOrigin(ent.Caller)))
+	}
+	ecsFields = append(ecsFields, zap.String("ecs.version", ecsVersion))
+
+	for _, f := range fields {
+		switch {
+		case f.Key == "error" && f.Type == zapcore.ErrorType:
+			f = zap.String("error.message", f.Interface.(error).Error())
+		case f.Key == TraceIDField:
+			f.Key = "trace.id"
+		case f.Key == SpanIDField:
+			f.Key = "span.id"
+		}
+		ecsFields = append(ecsFields, f)
+	}
+
+	return e.Encoder.EncodeEntry(ent, ecsFields)
+}
+
+// ecsOrigin encodes a caller as an ECS log.origin object.
+type ecsOrigin zapcore.EntryCaller
+
+func (o ecsOrigin) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("file.name", o.File)
+	enc.AddInt("file.line", o.Line)
+	if o.Function != "" {
+		enc.AddString("function", o.Function)
+	}
+	return nil
+}
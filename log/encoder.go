@@ -0,0 +1,165 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// TimeFormatRFC3339Micro formats times as RFC 3339 with microseconds, such as
+	// 2024-05-01T10:04:05.123456Z.
+	TimeFormatRFC3339Micro = "rfc3339micro"
+	// TimeFormatRFC3339 formats times as RFC 3339, such as 2024-05-01T10:04:05Z.
+	TimeFormatRFC3339 = "rfc3339"
+	// TimeFormatRFC3339Nano formats times as RFC 3339 with nanoseconds, trailing zeros
+	// removed, such as 2024-05-01T10:04:05.123456789Z.
+	TimeFormatRFC3339Nano = "rfc3339nano"
+	// TimeFormatEpochMillis formats times as the number of milliseconds since the Unix
+	// epoch, such as 1714557845123.
+	TimeFormatEpochMillis = "epoch_millis"
+)
+
+var timeFormatListString = []string{TimeFormatRFC3339Micro, TimeFormatRFC3339, TimeFormatRFC3339Nano, TimeFormatEpochMillis}
+
+// timeFormatLayouts maps the time formats to their Go time layouts.
+var timeFormatLayouts = map[string]string{
+	TimeFormatRFC3339Micro: "2006-01-02T15:04:05.000000Z07:00",
+	TimeFormatRFC3339:      time.RFC3339,
+	TimeFormatRFC3339Nano:  time.RFC3339Nano,
+}
+
+const (
+	// LevelCaseLower writes levels in lower case, such as info.
+	LevelCaseLower = "lower"
+	// LevelCaseUpper writes levels in upper case, such as INFO.
+	LevelCaseUpper = "upper"
+)
+
+var levelCaseListString = []string{LevelCaseLower, LevelCaseUpper}
+
+const (
+	// CallerShort writes callers as the package directory and file name, such as
+	// log/config.go:42.
+	CallerShort = "short"
+	// CallerFull writes callers as the full path of the file.
+	CallerFull = "full"
+)
+
+var callerFormatListString = []string{CallerShort, CallerFull}
+
+// encoderKeys lists the keys which can be renamed by EncoderKeys, in the order of the
+// entries.
+var encoderKeys = []string{"time", "level", "scope", "caller", "msg", "stack"}
+
+// encoderConfig returns the configuration of the console and JSON encoders, returning
+// all problems found with the options.
+func (o *Options) encoderConfig() (zapcore.EncoderConfig, []error) {
+	cfg := defaultEncoderConfig
+	var errs []error
+
+	location := time.UTC
+	if o.TimeZone != "" {
+		var err error
+		if location, err = time.LoadLocation(o.TimeZone); err != nil {
+			errs = append(errs, fmt.Errorf("invalid time zone: %v", err))
+			location = time.UTC
+		}
+	}
+
+	format := o.TimeFormat
+	if format == "" {
+		format = TimeFormatRFC3339Micro
+	}
+	switch {
+	case format == TimeFormatRFC3339Micro && location == time.UTC:
+		// formatDate is faster for the default format
+	case format == TimeFormatEpochMillis:
+		cfg.EncodeTime = func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+			enc.AppendInt64(t.UnixMilli())
+		}
+	default:
+		layout, ok := timeFormatLayouts[format]
+		if !ok {
+			// a custom layout must at least contain an element of the time
+			layout = format
+			if time.Unix(0, 0).UTC().Format(layout) == time.Unix(1e9+1, 0).UTC().Format(layout) {
+				errs = append(errs, fmt.Errorf("invalid time format '%s', must be one of %s or a Go time layout", o.TimeFormat, timeFormatListString))
+			}
+		}
+		cfg.EncodeTime = func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+			enc.AppendString(t.In(location).Format(layout))
+		}
+	}
+
+	switch o.LevelCase {
+	case "", LevelCaseLower:
+	case LevelCaseUpper:
+		cfg.EncodeLevel = zapcore.CapitalLevelEncoder
+	default:
+		errs = append(errs, fmt.Errorf("invalid level case '%s', must be one of %s", o.LevelCase, levelCaseListString))
+	}
+
+	switch o.CallerFormat {
+	case "", CallerShort:
+	case CallerFull:
+		cfg.EncodeCaller = zapcore.FullCallerEncoder
+	default:
+		errs = append(errs, fmt.Errorf("invalid caller format '%s', must be one of %s", o.CallerFormat, callerFormatListString))
+	}
+
+	names := make(map[string]string)
+	for _, entry := range strings.Split(o.EncoderKeys, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		key, name, ok := strings.Cut(entry, ":")
+		if !ok {
+			errs = append(errs, fmt.Errorf("invalid encoder key '%s', must be in the form <key>:<name>", entry))
+			continue
+		}
+
+		switch key {
+		case "time":
+			cfg.TimeKey = name
+		case "level":
+			cfg.LevelKey = name
+		case "scope":
+			cfg.NameKey = name
+		case "caller":
+			cfg.CallerKey = name
+		case "msg":
+			cfg.MessageKey = name
+		case "stack":
+			cfg.StacktraceKey = name
+		default:
+			errs = append(errs, fmt.Errorf("invalid encoder key '%s', must be one of %s", key, encoderKeys))
+		}
+	}
+	for i, name := range []string{cfg.TimeKey, cfg.LevelKey, cfg.NameKey, cfg.CallerKey, cfg.MessageKey, cfg.StacktraceKey} {
+		if other, ok := names[name]; ok && name != "" {
+			errs = append(errs, fmt.Errorf("invalid encoder keys, %s and %s are both named '%s'", other, encoderKeys[i], name))
+		}
+		names[name] = encoderKeys[i]
+	}
+
+	return cfg, errs
+}
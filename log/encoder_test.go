@@ -0,0 +1,83 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestEncoderTimeFormat(t *testing.T) {
+	ts := time.Date(2024, 5, 1, 10, 4, 5, 123456000, time.UTC)
+
+	cases := []struct {
+		format   string
+		timeZone string
+		expected string
+	}{
+		{"", "", `"2024-05-01T10:04:05.123456Z"`},
+		{TimeFormatRFC3339Micro, "Asia/Shanghai", `"2024-05-01T18:04:05.123456+08:00"`},
+		{TimeFormatRFC3339, "UTC", `"2024-05-01T10:04:05Z"`},
+		{TimeFormatRFC3339Nano, "UTC", `"2024-05-01T10:04:05.123456Z"`},
+		{TimeFormatEpochMillis, "Asia/Shanghai", `1714557845123`},
+		{"2006-01-02 15:04:05.000", "Asia/Shanghai", `"2024-05-01 18:04:05.123"`},
+	}
+
+	for _, c := range cases {
+		o := DefaultOptions()
+		o.TimeFormat = c.format
+		o.TimeZone = c.timeZone
+		cfg, errs := o.encoderConfig()
+		if len(errs) > 0 {
+			t.Fatalf("Got errors %v for format '%s', expected success", errs, c.format)
+		}
+
+		buf, _ := zapcore.NewJSONEncoder(cfg).EncodeEntry(zapcore.Entry{Time: ts}, nil)
+		if got := buf.String(); !strings.Contains(got, `"time":`+c.expected+",") {
+			t.Errorf("Got %s for format '%s' in '%s', expected time %s", got, c.format, c.timeZone, c.expected)
+		}
+	}
+}
+
+func TestEncoderConfig(t *testing.T) {
+	lines, err := captureStdout(func() {
+		o := testOptions()
+		o.JSONEncoding = true
+		o.LogCaller = true
+		o.TimeFormat = TimeFormatEpochMillis
+		o.EncoderKeys = "time:ts, msg:message,level:severity"
+		o.LevelCase = LevelCaseUpper
+		o.CallerFormat = CallerFull
+		if err := Configure(o); err != nil {
+			t.Errorf("Got err '%v', expecting success", err)
+		}
+
+		Info("configured")
+		_ = Sync()
+		_ = Configure(testOptions())
+	})
+	if err != nil {
+		t.Fatalf("Got error '%v', expected success", err)
+	}
+
+	pattern := `^{"severity":"INFO","ts":\d{13},"caller":"/.+/log/encoder_test.go:\d+","message":"configured"}$`
+	if match, _ := regexp.MatchString(pattern, lines[0]); !match {
+		t.Errorf("Got '%s', expecting to match '%s'", lines[0], pattern)
+	}
+}
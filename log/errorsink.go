@@ -0,0 +1,82 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+var (
+	// writeErrors counts the errors reported to every error sink.
+	writeErrors atomic.Uint64
+
+	writeErrorHandler atomic.Pointer[func(error)]
+)
+
+// WriteErrors returns the number of errors the logger ran into since the process
+// started, such as failures to write log entries to an output.
+func WriteErrors() uint64 {
+	return writeErrors.Load()
+}
+
+// SetWriteErrorHandler sets a function called with every error the logger runs into,
+// in addition to reporting it to the error output. This is the place to raise an alert
+// or export a metric when logging itself is broken. The handler must not log through
+// this package, and nil removes it.
+func SetWriteErrorHandler(handler func(err error)) {
+	if handler == nil {
+		writeErrorHandler.Store(nil)
+		return
+	}
+	writeErrorHandler.Store(&handler)
+}
+
+// errorSink reports logger errors to the error output. It also receives the errors
+// reported by Zap itself, as its error output.
+type errorSink struct {
+	ws zapcore.WriteSyncer
+}
+
+// report counts the error, passes it to the write error handler and writes it to the
+// error output.
+func (s *errorSink) report(err error) {
+	handleWriteError(err)
+	_, _ = fmt.Fprintf(s.ws, "%v log write error: %v\n", time.Now(), err)
+	_ = s.ws.Sync()
+}
+
+// Write reports an error formatted by Zap.
+func (s *errorSink) Write(p []byte) (int, error) {
+	handleWriteError(errors.New(strings.TrimSpace(string(p))))
+	return s.ws.Write(p)
+}
+
+// Sync syncs the error output.
+func (s *errorSink) Sync() error {
+	return s.ws.Sync()
+}
+
+func handleWriteError(err error) {
+	writeErrors.Add(1)
+	if handler := writeErrorHandler.Load(); handler != nil {
+		(*handler)(err)
+	}
+}
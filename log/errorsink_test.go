@@ -0,0 +1,71 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// failingCore fails to write every entry.
+type failingCore struct{}
+
+func (c failingCore) Enabled(zapcore.Level) bool                 { return true }
+func (c failingCore) With([]zapcore.Field) zapcore.Core          { return c }
+func (c failingCore) Write(zapcore.Entry, []zapcore.Field) error { return errors.New("disk full") }
+func (c failingCore) Sync() error                                { return nil }
+
+func (c failingCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return ce.AddCore(ent, c)
+}
+
+func TestErrorOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "err.log")
+
+	var handled []string
+	SetWriteErrorHandler(func(err error) { handled = append(handled, err.Error()) })
+	defer SetWriteErrorHandler(nil)
+
+	o := testOptions()
+	o.ErrorOutputPath = path
+	o.Sinks = []SinkOptions{{Core: failingCore{}}}
+	if err := Configure(o); err != nil {
+		t.Fatalf("Got err '%v', expecting success", err)
+	}
+	defer func() { _ = Configure(testOptions()) }()
+
+	count := WriteErrors()
+	Info("lost")
+	zap.L().Info("lost through zap")
+	if got := WriteErrors() - count; got != 2 {
+		t.Errorf("Got %d write errors, expecting 2", got)
+	}
+
+	if len(handled) != 2 || handled[0] != "disk full" || !strings.HasSuffix(handled[1], "write error: disk full") {
+		t.Errorf("Got %q, expecting the handler to be called with both errors", handled)
+	}
+
+	content, _ := os.ReadFile(path)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], "log write error: disk full") || !strings.HasSuffix(lines[1], "write error: disk full") {
+		t.Errorf("Got %q, expecting both errors in the error output", content)
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+// formatEntries are the entries written by the golden file tests.
+var formatEntries = []struct {
+	ent    zapcore.Entry
+	fields []zapcore.Field
+}{
+	{
+		ent: zapcore.Entry{
+			Level:      zapcore.InfoLevel,
+			Time:       time.Date(2024, 5, 1, 10, 4, 5, 123456789, time.UTC),
+			LoggerName: "engine",
+			Message:    "applying resources",
+			Caller: zapcore.EntryCaller{
+				Defined:  true,
+				File:     "/src/kusion/pkg/engine/apply.go",
+				Line:     42,
+				Function: "kusionstack.io/kusion/pkg/engine.Apply",
+			},
+		},
+		fields: []zapcore.Field{
+			zap.Int("count", 3),
+			zap.Bool("dry_run", false),
+			zap.Duration("elapsed", 1500*time.Millisecond),
+			zap.Any("labels", map[string]string{"app": "web", "tier": "frontend"}),
+		},
+	},
+	{
+		ent: zapcore.Entry{
+			Level:   zapcore.ErrorLevel,
+			Time:    time.Date(2024, 5, 1, 10, 4, 6, 0, time.UTC),
+			Message: `failed to apply "web"`,
+			Stack:   "main.main()\n\t/src/kusion/main.go:10",
+		},
+		fields: []zapcore.Field{
+			zap.Error(errors.New("connection refused")),
+			zap.String(TraceIDField, "4bf92f3577b34da6a3ce929d0e0e4736"),
+			zap.String(SpanIDField, "00f067aa0ba902b7"),
+		},
+	},
+	{
+		ent: zapcore.Entry{
+			Level:   zapcore.DebugLevel,
+			Time:    time.Date(2024, 5, 1, 10, 4, 7, 0, time.UTC),
+			Message: "multi\nline",
+		},
+		fields: []zapcore.Field{
+			zap.String("query", "a=b c"),
+			zap.String("empty", ""),
+			zap.Strings("ids", []string{"x", "y"}),
+		},
+	},
+}
+
+func TestFormatGolden(t *testing.T) {
+	for _, encoding := range []string{ConsoleEncoding, JSONEncoding, LogfmtEncoding, ECSEncoding, OTelEncoding} {
+		t.Run(encoding, func(t *testing.T) {
+			enc := newEncoder(encoding, DefaultOptions(), "out.log")
+			enc.AddString("request", "r1")
+
+			var got strings.Builder
+			for _, e := range formatEntries {
+				buf, err := enc.EncodeEntry(e.ent, e.fields)
+				if err != nil {
+					t.Fatalf("Got error '%v', expected success", err)
+				}
+				got.WriteString(buf.String())
+				buf.Free()
+			}
+
+			path := filepath.Join("testdata", "format", encoding+".golden")
+			if *update {
+				if err := os.WriteFile(path, []byte(got.String()), 0o644); err != nil {
+					t.Fatalf("Got error '%v', expected success", err)
+				}
+			}
+
+			expected, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Got error '%v', expected success", err)
+			}
+			if got.String() != string(expected) {
+				t.Errorf("Got:\n%s\nexpected:\n%s", got.String(), expected)
+			}
+		})
+	}
+}
+
+func TestFormatContextTrace(t *testing.T) {
+	cases := []struct {
+		encoding string
+		expected string
+	}{
+		{ECSEncoding, `"trace.id":"t1","span.id":"s1"`},
+		{OTelEncoding, `"TraceId":"t1","SpanId":"s1","Attributes":{"request":"r1"}}`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.encoding, func(t *testing.T) {
+			enc := newEncoder(c.encoding, DefaultOptions(), "out.log")
+			zap.String(TraceIDField, "t1").AddTo(enc)
+			zap.String(SpanIDField, "s1").AddTo(enc)
+			enc.AddString("request", "r1")
+
+			buf, err := enc.Clone().EncodeEntry(zapcore.Entry{Message: "traced"}, nil)
+			if err != nil {
+				t.Fatalf("Got error '%v', expected success", err)
+			}
+			defer buf.Free()
+
+			if got := buf.String(); !strings.Contains(got, c.expected) {
+				t.Errorf("Got %s, expected the context trace fields to be mapped to %s", got, c.expected)
+			}
+		})
+	}
+}
+
+func TestConfigureFormat(t *testing.T) {
+	lines, err := captureStdout(func() {
+		o := testOptions()
+		o.Format = LogfmtEncoding
+		if err := Configure(o); err != nil {
+			t.Errorf("Got err '%v', expecting success", err)
+		}
+
+		Info("formatted")
+		_ = Sync()
+		_ = Configure(testOptions())
+	})
+	if err != nil {
+		t.Fatalf("Got error '%v', expected success", err)
+	}
+
+	pattern := `^level=info time=\S+Z msg=formatted$`
+	if match, _ := regexp.MatchString(pattern, lines[0]); !match {
+		t.Errorf("Got '%s', expecting to match '%s'", lines[0], pattern)
+	}
+}
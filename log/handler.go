@@ -0,0 +1,146 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// maxLevelRequestSize bounds the size of a level change request body.
+const maxLevelRequestSize = 1 << 20
+
+// ScopeLevels describes the level settings of a scope, as reported and accepted by the
+// handler returned from NewLevelHandler.
+type ScopeLevels struct {
+	// Name is the name of the scope.
+	Name string `json:"name"`
+
+	// Description is the description of the scope. It is ignored in change requests.
+	Description string `json:"description,omitempty"`
+
+	// OutputLevel is the output level of the scope. In change requests, an empty
+	// value leaves the level unchanged.
+	OutputLevel string `json:"outputLevel,omitempty"`
+
+	// StackTraceLevel is the stack trace level of the scope. In change requests, an
+	// empty value leaves the level unchanged.
+	StackTraceLevel string `json:"stackTraceLevel,omitempty"`
+
+	// LogCallers reports whether the scope logs callers. In change requests, a
+	// missing value leaves the setting unchanged.
+	LogCallers *bool `json:"logCallers,omitempty"`
+}
+
+// NewLevelHandler returns an http.Handler for inspecting and changing scope levels at runtime,
+// typically mounted at /debug/loglevel.
+//
+// GET responds with a JSON array of ScopeLevels for every registered scope, sorted by name.
+// PUT and POST accept a JSON array of ScopeLevels naming the scopes to change. All changes are
+// validated before any is applied, and the response is the same as for GET.
+func NewLevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut, http.MethodPost:
+			if err := updateScopeLevels(w, r); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		default:
+			w.Header().Set("Allow", strings.Join([]string{http.MethodGet, http.MethodPut, http.MethodPost}, ", "))
+			http.Error(w, fmt.Sprintf("method %s not allowed", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(currentScopeLevels())
+	})
+}
+
+// currentScopeLevels returns the levels of every registered scope, sorted by name.
+func currentScopeLevels() []ScopeLevels {
+	all := Scopes()
+	levels := make([]ScopeLevels, 0, len(all))
+	for _, name := range scopeNames() {
+		s := all[name]
+		logCallers := s.GetLogCallers()
+		levels = append(levels, ScopeLevels{
+			Name:            name,
+			Description:     s.Description(),
+			OutputLevel:     levelToString[s.GetOutputLevel()],
+			StackTraceLevel: levelToString[s.GetStackTraceLevel()],
+			LogCallers:      &logCallers,
+		})
+	}
+	return levels
+}
+
+// updateScopeLevels applies the changes described by the request body.
+func updateScopeLevels(w http.ResponseWriter, r *http.Request) error {
+	var changes []ScopeLevels
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxLevelRequestSize)).Decode(&changes); err != nil {
+		return fmt.Errorf("invalid request body: %v", err)
+	}
+
+	type update struct {
+		scope           *Scope
+		outputLevel     *Level
+		stackTraceLevel *Level
+		logCallers      *bool
+	}
+
+	updates := make([]update, 0, len(changes))
+	for _, c := range changes {
+		u := update{scope: FindScope(c.Name), logCallers: c.LogCallers}
+		if u.scope == nil {
+			return fmt.Errorf("unknown scope '%s' specified, must be one of %s", c.Name, scopeNames())
+		}
+
+		if c.OutputLevel != "" {
+			level, ok := stringToLevel[c.OutputLevel]
+			if !ok {
+				return fmt.Errorf("invalid output level '%s' for scope '%s', must be one of %s", c.OutputLevel, c.Name, levelListString)
+			}
+			u.outputLevel = &level
+		}
+
+		if c.StackTraceLevel != "" {
+			level, ok := stringToLevel[c.StackTraceLevel]
+			if !ok {
+				return fmt.Errorf("invalid stack trace level '%s' for scope '%s', must be one of %s", c.StackTraceLevel, c.Name, levelListString)
+			}
+			u.stackTraceLevel = &level
+		}
+
+		updates = append(updates, u)
+	}
+
+	for _, u := range updates {
+		if u.outputLevel != nil {
+			u.scope.SetOutputLevel(*u.outputLevel)
+		}
+		if u.stackTraceLevel != nil {
+			u.scope.SetStackTraceLevel(*u.stackTraceLevel)
+		}
+		if u.logCallers != nil {
+			u.scope.SetLogCallers(*u.logCallers)
+		}
+	}
+
+	return nil
+}
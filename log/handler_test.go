@@ -0,0 +1,129 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func doLevelRequest(t *testing.T, method, body string) (*httptest.ResponseRecorder, map[string]ScopeLevels) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	NewLevelHandler().ServeHTTP(rec, httptest.NewRequest(method, "/debug/loglevel", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		return rec, nil
+	}
+
+	var levels []ScopeLevels
+	if err := json.Unmarshal(rec.Body.Bytes(), &levels); err != nil {
+		t.Fatalf("Got error '%v' decoding '%s', expected success", err, rec.Body.String())
+	}
+
+	byName := make(map[string]ScopeLevels, len(levels))
+	for _, l := range levels {
+		byName[l.Name] = l
+	}
+	return rec, byName
+}
+
+func TestLevelHandlerGet(t *testing.T) {
+	s := RegisterScope("handler", "handler scope")
+	_ = Configure(testOptions())
+	s.SetOutputLevel(WarnLevel)
+
+	rec, levels := doLevelRequest(t, http.MethodGet, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Got status %d, expecting %d", rec.Code, http.StatusOK)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Got content type %q, expecting application/json", ct)
+	}
+
+	got := levels["handler"]
+	if got.Description != "handler scope" || got.OutputLevel != "warn" || got.StackTraceLevel != "none" ||
+		got.LogCallers == nil || *got.LogCallers {
+		t.Errorf("Got %+v, expecting the levels of the handler scope", got)
+	}
+	if levels[DefaultLoggerName].OutputLevel != "info" {
+		t.Errorf("Got %+v, expecting the levels of the default scope", levels[DefaultLoggerName])
+	}
+}
+
+func TestLevelHandlerUpdate(t *testing.T) {
+	s := RegisterScope("handler", "")
+	_ = Configure(testOptions())
+
+	for _, method := range []string{http.MethodPut, http.MethodPost} {
+		t.Run(method, func(t *testing.T) {
+			s.SetOutputLevel(InfoLevel)
+
+			rec, levels := doLevelRequest(t, method,
+				`[{"name":"handler","outputLevel":"debug","stackTraceLevel":"error","logCallers":true}]`)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Got status %d (%s), expecting %d", rec.Code, rec.Body.String(), http.StatusOK)
+			}
+
+			if s.GetOutputLevel() != DebugLevel || s.GetStackTraceLevel() != ErrorLevel || !s.GetLogCallers() {
+				t.Errorf("Got %v/%v/%v, expecting debug/error/true",
+					s.GetOutputLevel(), s.GetStackTraceLevel(), s.GetLogCallers())
+			}
+			if levels["handler"].OutputLevel != "debug" {
+				t.Errorf("Got %+v, expecting the updated levels in the response", levels["handler"])
+			}
+			if defaultScope.GetOutputLevel() != InfoLevel {
+				t.Errorf("Got %v, expecting the default scope to be unchanged", defaultScope.GetOutputLevel())
+			}
+		})
+	}
+}
+
+func TestLevelHandlerErrors(t *testing.T) {
+	s := RegisterScope("handler", "")
+	_ = Configure(testOptions())
+
+	cases := []struct {
+		method string
+		body   string
+		code   int
+		err    string
+	}{
+		{http.MethodDelete, "", http.StatusMethodNotAllowed, "method DELETE not allowed"},
+		{http.MethodPut, "{", http.StatusBadRequest, "invalid request body"},
+		{http.MethodPut, `[{"name":"nosuchscope","outputLevel":"debug"}]`, http.StatusBadRequest, "unknown scope 'nosuchscope'"},
+		{http.MethodPut, `[{"name":"handler","outputLevel":"debug"},{"name":"default","outputLevel":"loud"}]`,
+			http.StatusBadRequest, "invalid output level 'loud' for scope 'default'"},
+		{http.MethodPut, `[{"name":"handler","stackTraceLevel":"loud"}]`, http.StatusBadRequest, "invalid stack trace level 'loud'"},
+	}
+	for i, c := range cases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			rec, _ := doLevelRequest(t, c.method, c.body)
+			if rec.Code != c.code {
+				t.Errorf("Got status %d, expecting %d", rec.Code, c.code)
+			}
+			if !strings.Contains(rec.Body.String(), c.err) {
+				t.Errorf("Got body '%s', expecting it to contain '%s'", rec.Body.String(), c.err)
+			}
+			if s.GetOutputLevel() != InfoLevel {
+				t.Errorf("Got %v, expecting a rejected request to leave levels unchanged", s.GetOutputLevel())
+			}
+		})
+	}
+}
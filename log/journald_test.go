@@ -0,0 +1,119 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !windows
+
+package log
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJournaldSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("Got error '%v', expected success", err)
+	}
+	defer conn.Close()
+
+	o := testOptions()
+	o.LogCaller = true
+	o.Sinks = []SinkOptions{{Path: "journald://" + path + "?tag=app", Encoding: JSONEncoding}}
+	if err := Configure(o); err != nil {
+		t.Fatalf("Got err '%v', expecting success", err)
+	}
+	defer func() { _ = Configure(testOptions()) }()
+
+	_, _ = captureStdout(func() {
+		RegisterScope("journald", "").Warn("line1\nline2")
+	})
+
+	buf := make([]byte, 4096)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("Got error '%v', expected an entry", err)
+	}
+	fields := parseJournalFields(t, buf[:n])
+
+	if got := fields["MESSAGE"]; !strings.Contains(got, `"msg":"line1\nline2"`) {
+		t.Errorf("Got MESSAGE %q, expected the JSON encoded entry", got)
+	}
+	expected := map[string]string{
+		"PRIORITY":          "4",
+		"SYSLOG_IDENTIFIER": "app",
+		"LOG_SCOPE":         "journald",
+	}
+	for k, v := range expected {
+		if fields[k] != v {
+			t.Errorf("Got %s=%q, expected %q", k, fields[k], v)
+		}
+	}
+	if !strings.HasSuffix(fields["CODE_FILE"], "journald_test.go") || fields["CODE_LINE"] == "" {
+		t.Errorf("Got CODE_FILE=%q CODE_LINE=%q, expected the caller", fields["CODE_FILE"], fields["CODE_LINE"])
+	}
+}
+
+func TestAppendJournalField(t *testing.T) {
+	var buf bytes.Buffer
+	appendJournalField(&buf, "A", []byte("single"))
+	appendJournalField(&buf, "B", []byte("multi\nline"))
+
+	expected := "A=single\nB\n\x0a\x00\x00\x00\x00\x00\x00\x00multi\nline\n"
+	if got := buf.String(); got != expected {
+		t.Errorf("Got %q, expected %q", got, expected)
+	}
+}
+
+func TestInvalidJournaldSink(t *testing.T) {
+	o := testOptions()
+	o.Sinks = []SinkOptions{{Path: "journald://" + filepath.Join(t.TempDir(), "missing.sock")}}
+	if err := Configure(o); err == nil || !strings.Contains(err.Error(), "failed to connect to journald") {
+		t.Errorf("Got %v, expecting a connection error", err)
+	}
+	_ = Configure(testOptions())
+}
+
+// parseJournalFields decodes a datagram in the journal native protocol format.
+func parseJournalFields(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+
+	fields := make(map[string]string)
+	for len(data) > 0 {
+		i := bytes.IndexAny(data, "=\n")
+		if i < 0 {
+			t.Fatalf("Got malformed field %q", data)
+		}
+		name := string(data[:i])
+		if data[i] == '=' {
+			end := bytes.IndexByte(data[i:], '\n')
+			fields[name] = string(data[i+1 : i+end])
+			data = data[i+end+1:]
+			continue
+		}
+
+		data = data[i+1:]
+		size := int(binary.LittleEndian.Uint64(data))
+		fields[name] = string(data[8 : 8+size])
+		data = data[8+size+1:]
+	}
+	return fields
+}
@@ -0,0 +1,44 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"flag"
+
+	"k8s.io/klog/v2"
+)
+
+// klogVerbosity is the klog verbosity set by RedirectKlog. It is high enough to let all
+// V-levels through to the scope, which then decides what to output.
+const klogVerbosity = "10"
+
+// RedirectKlog routes the output of k8s.io/klog/v2 through the given scope, as with NewLogr.
+// It returns a function restoring klog's own output and verbosity.
+//
+// klog checks its own verbosity before handing V-level messages over, so RedirectKlog sets the
+// global klog verbosity, as set by klog's -v flag, to 10 until restored, to let the scope's level
+// decide whether V-level messages are output.
+func RedirectKlog(s *Scope) func() {
+	fs := flag.NewFlagSet("klog", flag.ContinueOnError)
+	klog.InitFlags(fs)
+	verbosity := fs.Lookup("v").Value.String()
+	_ = fs.Set("v", klogVerbosity)
+
+	klog.SetLogger(NewLogr(s))
+	return func() {
+		klog.ClearLogger()
+		_ = fs.Set("v", verbosity)
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+var logfmtPool = buffer.NewPool()
+
+// logfmtEncoder formats entries as logfmt, a line of space-separated key=value pairs
+// such as:
+//
+//	level=info time=2024-05-01T10:04:05.123456Z scope=engine msg="applying resources" count=3
+//
+// Values containing spaces, quotes, equal signs or control characters are quoted, and
+// objects and arrays are written as quoted JSON.
+type logfmtEncoder struct {
+	// Encoder encodes the entries as JSON, which are then converted pair by pair, so
+	// that the keys, order and time format follow the encoder configuration.
+	zapcore.Encoder
+}
+
+func newLogfmtEncoder(encCfg zapcore.EncoderConfig) zapcore.Encoder {
+	return &logfmtEncoder{Encoder: zapcore.NewJSONEncoder(encCfg)}
+}
+
+func (e *logfmtEncoder) Clone() zapcore.Encoder {
+	return &logfmtEncoder{Encoder: e.Encoder.Clone()}
+}
+
+func (e *logfmtEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	encoded, err := e.Encoder.EncodeEntry(ent, fields)
+	if err != nil {
+		return nil, err
+	}
+	defer encoded.Free()
+
+	pairs, err := decodeFields(encoded.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	buf := logfmtPool.Get()
+	for i, p := range pairs {
+		if i > 0 {
+			buf.AppendByte(' ')
+		}
+		appendLogfmtKey(buf, p.key)
+		buf.AppendByte('=')
+
+		var s string
+		switch {
+		case json.Unmarshal(p.value, &s) == nil:
+			appendLogfmtValue(buf, s)
+		case len(p.value) > 0 && (p.value[0] == '{' || p.value[0] == '['):
+			appendLogfmtValue(buf, string(p.value))
+		default:
+			// numbers, booleans and null
+			buf.Write(p.value)
+		}
+	}
+	buf.AppendByte('\n')
+	return buf, nil
+}
+
+// appendLogfmtKey appends the key, with the characters logfmt doesn't allow in keys
+// replaced by underscores.
+func appendLogfmtKey(buf *buffer.Buffer, key string) {
+	if key == "" {
+		buf.AppendByte('_')
+		return
+	}
+	buf.AppendString(strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' || !unicode.IsPrint(r) {
+			return '_'
+		}
+		return r
+	}, key))
+}
+
+// appendLogfmtValue appends the value, quoted if needed.
+func appendLogfmtValue(buf *buffer.Buffer, value string) {
+	if value != "" && strings.IndexFunc(value, func(r rune) bool {
+		return r <= ' ' || r == '=' || r == '"' || !unicode.IsPrint(r)
+	}) < 0 {
+		buf.AppendString(value)
+		return
+	}
+	buf.Write(strconv.AppendQuote(nil, value))
+}
@@ -26,24 +26,35 @@ import (
 
 // callerSkipOffset is how many callers to pop off the stack to determine the caller function locality, used for
 // adding file/line number to log output.
-const callerSkipOffset = 3
+const callerSkipOffset = 2
 
-var defaultLogger *logger
+// defaultScope is the scope used by the package-level logging functions. It skips one extra
+// caller so that the reported location is the caller of the package-level function.
+var defaultScope = registerScope(DefaultLoggerName, "Unscoped logging messages.", 1)
+
+var defaultLogger = defaultScope.logger
 
 // logger collects all the global state of the logging setup.
 type logger struct {
 	name       string
 	callerSkip int
 
+	// parent is the logger whose level settings are shared by this logger, set for
+	// child loggers created by with.
+	parent *logger
+	// fields are attached to every entry written by this logger.
+	fields []zapcore.Field
+
 	outputLevel     atomic.Value
 	stackTraceLevel atomic.Value
 	logCallers      atomic.Value
+	sampler         atomic.Pointer[sampler]
 }
 
 // Info outputs a message at info level.
 func (l *logger) Info(field any) {
 	if l.GetOutputLevel() >= InfoLevel {
-		l.output(zapcore.InfoLevel, fmt.Sprint(field))
+		l.output(zapcore.InfoLevel, fmt.Sprint(field), nil)
 	}
 }
 
@@ -51,7 +62,14 @@ func (l *logger) Info(field any) {
 func (l *logger) Infof(format string, fields ...any) {
 	if l.GetOutputLevel() >= InfoLevel {
 		msg := maybeSprintf(format, fields...)
-		l.output(zapcore.InfoLevel, msg)
+		l.output(zapcore.InfoLevel, msg, nil)
+	}
+}
+
+// InfoS outputs a message at info level with the given key/value pairs attached as structured fields.
+func (l *logger) InfoS(msg string, keysAndValues ...any) {
+	if l.GetOutputLevel() >= InfoLevel {
+		l.output(zapcore.InfoLevel, msg, toFields(keysAndValues))
 	}
 }
 
@@ -63,7 +81,7 @@ func (l *logger) InfoEnabled() bool {
 // Debug outputs a message at debug level.
 func (l *logger) Debug(field any) {
 	if l.GetOutputLevel() >= DebugLevel {
-		l.output(zapcore.DebugLevel, fmt.Sprint(field))
+		l.output(zapcore.DebugLevel, fmt.Sprint(field), nil)
 	}
 }
 
@@ -71,7 +89,14 @@ func (l *logger) Debug(field any) {
 func (l *logger) Debugf(format string, fields ...any) {
 	if l.GetOutputLevel() >= DebugLevel {
 		msg := maybeSprintf(format, fields...)
-		l.output(zapcore.DebugLevel, msg)
+		l.output(zapcore.DebugLevel, msg, nil)
+	}
+}
+
+// DebugS outputs a message at debug level with the given key/value pairs attached as structured fields.
+func (l *logger) DebugS(msg string, keysAndValues ...any) {
+	if l.GetOutputLevel() >= DebugLevel {
+		l.output(zapcore.DebugLevel, msg, toFields(keysAndValues))
 	}
 }
 
@@ -83,7 +108,7 @@ func (l *logger) DebugEnabled() bool {
 // Warn outputs a message at warn level.
 func (l *logger) Warn(field any) {
 	if l.GetOutputLevel() >= WarnLevel {
-		l.output(zapcore.WarnLevel, fmt.Sprint(field))
+		l.output(zapcore.WarnLevel, fmt.Sprint(field), nil)
 	}
 }
 
@@ -91,7 +116,14 @@ func (l *logger) Warn(field any) {
 func (l *logger) Warnf(format string, fields ...any) {
 	if l.GetOutputLevel() >= WarnLevel {
 		msg := maybeSprintf(format, fields...)
-		l.output(zapcore.WarnLevel, msg)
+		l.output(zapcore.WarnLevel, msg, nil)
+	}
+}
+
+// WarnS outputs a message at warn level with the given key/value pairs attached as structured fields.
+func (l *logger) WarnS(msg string, keysAndValues ...any) {
+	if l.GetOutputLevel() >= WarnLevel {
+		l.output(zapcore.WarnLevel, msg, toFields(keysAndValues))
 	}
 }
 
@@ -103,7 +135,7 @@ func (l *logger) WarnEnabled() bool {
 // Error outputs a message at error level.
 func (l *logger) Error(field any) {
 	if l.GetOutputLevel() >= ErrorLevel {
-		l.output(zapcore.ErrorLevel, fmt.Sprint(field))
+		l.output(zapcore.ErrorLevel, fmt.Sprint(field), nil)
 	}
 }
 
@@ -111,7 +143,15 @@ func (l *logger) Error(field any) {
 func (l *logger) Errorf(format string, fields ...any) {
 	if l.GetOutputLevel() >= ErrorLevel {
 		msg := maybeSprintf(format, fields...)
-		l.output(zapcore.ErrorLevel, msg)
+		l.output(zapcore.ErrorLevel, msg, nil)
+	}
+}
+
+// ErrorS outputs a message at error level with the error and the given key/value pairs attached as structured fields.
+// The error is omitted if it is nil.
+func (l *logger) ErrorS(err error, msg string, keysAndValues ...any) {
+	if l.GetOutputLevel() >= ErrorLevel {
+		l.output(zapcore.ErrorLevel, msg, errorFields(err, keysAndValues))
 	}
 }
 
@@ -123,7 +163,7 @@ func (l *logger) ErrorEnabled() bool {
 // Fatal outputs a message at fatal level.
 func (l *logger) Fatal(field any) {
 	if l.GetOutputLevel() >= FatalLevel {
-		l.output(zapcore.FatalLevel, fmt.Sprint(field))
+		l.output(zapcore.FatalLevel, fmt.Sprint(field), nil)
 	}
 }
 
@@ -131,7 +171,15 @@ func (l *logger) Fatal(field any) {
 func (l *logger) Fatalf(format string, fields ...any) {
 	if l.GetOutputLevel() >= FatalLevel {
 		msg := maybeSprintf(format, fields...)
-		l.output(zapcore.FatalLevel, msg)
+		l.output(zapcore.FatalLevel, msg, nil)
+	}
+}
+
+// FatalS outputs a message at fatal level with the error and the given key/value pairs attached as structured fields.
+// The error is omitted if it is nil.
+func (l *logger) FatalS(err error, msg string, keysAndValues ...any) {
+	if l.GetOutputLevel() >= FatalLevel {
+		l.output(zapcore.FatalLevel, msg, errorFields(err, keysAndValues))
 	}
 }
 
@@ -142,59 +190,107 @@ func (l *logger) FatalEnabled() bool {
 
 // SetOutputLevel adjusts the output level associated with this logger.
 func (l *logger) SetOutputLevel(level Level) {
-	l.outputLevel.Store(level)
+	l.root().outputLevel.Store(level)
 }
 
 // GetOutputLevel returns the output level associated with this logger.
 func (l *logger) GetOutputLevel() Level {
-	return l.outputLevel.Load().(Level)
+	return l.root().outputLevel.Load().(Level)
 }
 
 // SetStackTraceLevel adjusts the stack tracing level associated with this logger.
 func (l *logger) SetStackTraceLevel(level Level) {
-	l.stackTraceLevel.Store(level)
+	l.root().stackTraceLevel.Store(level)
 }
 
 // GetStackTraceLevel returns the stack tracing level associated with this logger.
 func (l *logger) GetStackTraceLevel() Level {
-	return l.stackTraceLevel.Load().(Level)
+	return l.root().stackTraceLevel.Load().(Level)
 }
 
 // SetLogCallers adjusts the output level associated with this logger.
 func (l *logger) SetLogCallers(logCallers bool) {
-	l.logCallers.Store(logCallers)
+	l.root().logCallers.Store(logCallers)
 }
 
 // GetLogCallers returns the output level associated with this logger.
 func (l *logger) GetLogCallers() bool {
-	return l.logCallers.Load().(bool)
+	return l.root().logCallers.Load().(bool)
+}
+
+// root returns the logger holding the level settings used by this logger.
+func (l *logger) root() *logger {
+	if l.parent != nil {
+		return l.parent
+	}
+	return l
+}
+
+// with returns a child logger that shares the level settings of this logger and
+// attaches the given key/value pairs to every entry it writes.
+func (l *logger) with(keysAndValues []any) *logger {
+	fields := toFields(keysAndValues)
+	child := &logger{
+		name:   l.name,
+		parent: l.root(),
+		fields: make([]zapcore.Field, 0, len(l.fields)+len(fields)),
+	}
+	child.fields = append(child.fields, l.fields...)
+	child.fields = append(child.fields, fields...)
+	return child
 }
 
 // output writes the data to the log files.
-func (l *logger) output(level zapcore.Level, msg string) {
+func (l *logger) output(level zapcore.Level, msg string, fields []zapcore.Field) {
+	// skip this function too
+	l.outputSkip(l.callerSkip+1, level, msg, fields)
+}
+
+// outputSkip is like output, reporting the caller skip frames above the caller of
+// outputSkip.
+func (l *logger) outputSkip(skip int, level zapcore.Level, msg string, fields []zapcore.Field) {
 	e := zapcore.Entry{
 		Message: msg,
 		Level:   level,
 		Time:    time.Now(),
 	}
-	if l.name != DefaultLoggerName {
-		e.LoggerName = l.name
-	}
 
 	if l.GetLogCallers() {
-		e.Caller = zapcore.NewEntryCaller(runtime.Caller(l.callerSkip + callerSkipOffset))
+		e.Caller = zapcore.NewEntryCaller(runtime.Caller(skip + callerSkipOffset))
 	}
 
-	thresh := toLevel[level]
+	l.write(e, fields)
+}
+
+// write completes the entry with this logger's name, stack trace and bound fields and
+// writes it to the log files, unless it is suppressed by sampling.
+func (l *logger) write(e zapcore.Entry, fields []zapcore.Field) {
+	root := l.root()
+	if s := root.sampler.Load(); s != nil && !s.sample(root, e) {
+		return
+	}
+	l.emit(e, fields)
+}
+
+// emit writes the entry regardless of sampling.
+func (l *logger) emit(e zapcore.Entry, fields []zapcore.Field) {
+	if l.name != DefaultLoggerName {
+		e.LoggerName = l.name
+	}
+
+	thresh := toLevel[e.Level]
 	if l.GetStackTraceLevel() >= thresh {
 		e.Stack = zap.Stack("").String
 	}
 
+	if len(l.fields) > 0 {
+		fields = append(l.fields[:len(l.fields):len(l.fields)], fields...)
+	}
+
 	ft := funcs.Load().(functionTable)
 	if ft.write != nil {
-		if err := ft.write(e, nil); err != nil {
-			_, _ = fmt.Fprintf(ft.errorSink, "%v log write error: %v\n", time.Now(), err)
-			_ = ft.errorSink.Sync()
+		if err := ft.write(e, fields); err != nil {
+			ft.errorSink.report(err)
 		}
 	}
 }
@@ -210,6 +306,11 @@ func Infof(format string, fields ...any) {
 	defaultLogger.Infof(format, fields...)
 }
 
+// InfoS logs to the INFO log with the given key/value pairs attached as structured fields.
+func InfoS(msg string, keysAndValues ...any) {
+	defaultLogger.InfoS(msg, keysAndValues...)
+}
+
 // Debug logs to the DEBUG log.
 func Debug(field any) {
 	defaultLogger.Debug(field)
@@ -220,6 +321,11 @@ func Debugf(format string, fields ...any) {
 	defaultLogger.Debugf(format, fields...)
 }
 
+// DebugS logs to the DEBUG log with the given key/value pairs attached as structured fields.
+func DebugS(msg string, keysAndValues ...any) {
+	defaultLogger.DebugS(msg, keysAndValues...)
+}
+
 // Warn logs to the WARN log.
 func Warn(field any) {
 	defaultLogger.Warn(field)
@@ -230,6 +336,11 @@ func Warnf(format string, fields ...any) {
 	defaultLogger.Warnf(format, fields...)
 }
 
+// WarnS logs to the WARN log with the given key/value pairs attached as structured fields.
+func WarnS(msg string, keysAndValues ...any) {
+	defaultLogger.WarnS(msg, keysAndValues...)
+}
+
 // Error logs to the ERROR log.
 func Error(field any) {
 	defaultLogger.Error(field)
@@ -240,6 +351,11 @@ func Errorf(format string, fields ...any) {
 	defaultLogger.Errorf(format, fields...)
 }
 
+// ErrorS logs to the ERROR log with the error and the given key/value pairs attached as structured fields.
+func ErrorS(err error, msg string, keysAndValues ...any) {
+	defaultLogger.ErrorS(err, msg, keysAndValues...)
+}
+
 // Fatal logs to the FATAL log.
 func Fatal(field any) {
 	defaultLogger.Fatal(field)
@@ -250,6 +366,17 @@ func Fatalf(format string, fields ...any) {
 	defaultLogger.Fatalf(format, fields...)
 }
 
+// FatalS logs to the FATAL log with the error and the given key/value pairs attached as structured fields.
+func FatalS(err error, msg string, keysAndValues ...any) {
+	defaultLogger.FatalS(err, msg, keysAndValues...)
+}
+
+// With returns a child of the default scope that attaches the given key/value pairs
+// to every entry it writes. The child shares the levels of the default scope.
+func With(keysAndValues ...any) *Scope {
+	return defaultScope.With(keysAndValues...)
+}
+
 func maybeSprintf(format string, args ...any) string {
 	msg := format
 	if len(args) > 0 {
@@ -257,3 +384,42 @@ func maybeSprintf(format string, args ...any) string {
 	}
 	return msg
 }
+
+// toFields converts alternating key/value pairs into zap fields. Values that are already zap fields are
+// used as they are. A key without a value is recorded with the value "(MISSING)".
+func toFields(keysAndValues []any) []zapcore.Field {
+	if len(keysAndValues) == 0 {
+		return nil
+	}
+
+	fields := make([]zapcore.Field, 0, len(keysAndValues)/2+1)
+	for i := 0; i < len(keysAndValues); i++ {
+		if f, ok := keysAndValues[i].(zapcore.Field); ok {
+			fields = append(fields, f)
+			continue
+		}
+
+		key, ok := keysAndValues[i].(string)
+		if !ok {
+			key = fmt.Sprint(keysAndValues[i])
+		}
+
+		if i+1 == len(keysAndValues) {
+			fields = append(fields, zap.String(key, "(MISSING)"))
+			break
+		}
+
+		i++
+		fields = append(fields, zap.Any(key, keysAndValues[i]))
+	}
+
+	return fields
+}
+
+// errorFields converts the key/value pairs into zap fields, preceded by the error if it isn't nil.
+func errorFields(err error, keysAndValues []any) []zapcore.Field {
+	if err == nil {
+		return toFields(keysAndValues)
+	}
+	return append([]zapcore.Field{zap.Error(err)}, toFields(keysAndValues)...)
+}
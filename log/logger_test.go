@@ -15,9 +15,12 @@
 package log
 
 import (
+	"errors"
 	"regexp"
 	"strconv"
 	"testing"
+
+	"go.uber.org/zap"
 )
 
 func testOptions() *Options {
@@ -156,6 +159,39 @@ func TestBasic(t *testing.T) {
 			wantExit:   true,
 			stackLevel: DebugLevel,
 		},
+		{
+			f:   func() { l.InfoS("Hello", "stack", "demo", "replicas", 3) },
+			pat: timePattern + "\tinfo\tbeep\tHello\t\\{\"stack\": \"demo\", \"replicas\": 3\\}",
+		},
+		{
+			f: func() { l.InfoS("Hello", "stack", "demo", "replicas", 3) },
+			pat: "{\"level\":\"info\",\"time\":\"" + timePattern + "\",\"scope\":\"beep\",\"msg\":\"Hello\"," +
+				"\"stack\":\"demo\",\"replicas\":3}",
+			json: true,
+		},
+		{
+			f:   func() { l.DebugS("Hello", zap.String("project", "p"), "dangling") },
+			pat: timePattern + "\tdebug\tbeep\tHello\t\\{\"project\": \"p\", \"dangling\": \"\\(MISSING\\)\"\\}",
+		},
+		{
+			f:   func() { l.WarnS("Hello", "workspace", "dev") },
+			pat: timePattern + "\twarn\tbeep\tHello\t\\{\"workspace\": \"dev\"\\}",
+		},
+		{
+			f: func() { l.ErrorS(errors.New("boom"), "Hello", "workspace", "dev") },
+			pat: "{\"level\":\"error\",\"time\":\"" + timePattern + "\",\"scope\":\"beep\",\"msg\":\"Hello\"," +
+				"\"error\":\"boom\",\"workspace\":\"dev\"}",
+			json: true,
+		},
+		{
+			f:   func() { l.ErrorS(nil, "Hello", "workspace", "dev") },
+			pat: timePattern + "\terror\tbeep\tHello\t\\{\"workspace\": \"dev\"\\}",
+		},
+		{
+			f:        func() { l.FatalS(errors.New("boom"), "Hello") },
+			pat:      timePattern + "\tfatal\tbeep\tHello\t\\{\"error\": \"boom\"\\}",
+			wantExit: true,
+		},
 	}
 
 	for i, c := range cases {
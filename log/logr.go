@@ -0,0 +1,111 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"strings"
+
+	"github.com/go-logr/logr"
+	"go.uber.org/zap/zapcore"
+)
+
+// logrSink should implement the logr.LogSink and logr.CallDepthLogSink interfaces.
+var (
+	_ logr.LogSink          = &logrSink{}
+	_ logr.CallDepthLogSink = &logrSink{}
+)
+
+// NewLogr returns a logr.Logger that writes through the given scope, for use with libraries
+// such as controller-runtime and client-go.
+//
+// V-level 0 is logged at info level and all higher V-levels at debug level, while Error is
+// logged at error level. WithValues attaches structured fields. WithName switches to a scope
+// named after the joined names, such as "engine/controller", registering it if needed.
+// Characters that aren't allowed in scope names are replaced with underscores.
+func NewLogr(s *Scope) logr.Logger {
+	return logr.New(&logrSink{logger: s.logger.with(nil)})
+}
+
+type logrSink struct {
+	logger *logger
+}
+
+// Init receives the number of call frames logr adds, to skip them when logging callers.
+func (s *logrSink) Init(info logr.RuntimeInfo) {
+	s.logger.callerSkip = info.CallDepth
+}
+
+// Enabled reports whether the scope outputs messages at the given V-level.
+func (s *logrSink) Enabled(level int) bool {
+	if level > 0 {
+		return s.logger.DebugEnabled()
+	}
+	return s.logger.InfoEnabled()
+}
+
+// Info logs a non-error message at info level for V-level 0 and at debug level otherwise.
+func (s *logrSink) Info(level int, msg string, keysAndValues ...any) {
+	if !s.Enabled(level) {
+		return
+	}
+
+	if level > 0 {
+		s.logger.output(zapcore.DebugLevel, msg, toFields(keysAndValues))
+	} else {
+		s.logger.output(zapcore.InfoLevel, msg, toFields(keysAndValues))
+	}
+}
+
+// Error logs an error message at error level.
+func (s *logrSink) Error(err error, msg string, keysAndValues ...any) {
+	if s.logger.ErrorEnabled() {
+		s.logger.output(zapcore.ErrorLevel, msg, errorFields(err, keysAndValues))
+	}
+}
+
+// WithValues returns a sink that attaches the key/value pairs to every message.
+func (s *logrSink) WithValues(keysAndValues ...any) logr.LogSink {
+	return s.derive(s.logger.with(keysAndValues))
+}
+
+// WithName returns a sink writing through the scope named after this sink's scope and name.
+func (s *logrSink) WithName(name string) logr.LogSink {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(":,. ", r) {
+			return '_'
+		}
+		return r
+	}, name)
+	if s.logger.name != DefaultLoggerName {
+		name = s.logger.name + "/" + name
+	}
+
+	l := RegisterScope(name, "Messages logged through logr.").logger.with(nil)
+	l.fields = append(l.fields, s.logger.fields...)
+	return s.derive(l)
+}
+
+// WithCallDepth returns a sink that skips depth more call frames when logging callers.
+func (s *logrSink) WithCallDepth(depth int) logr.LogSink {
+	l := s.logger.with(nil)
+	l.callerSkip = s.logger.callerSkip + depth
+	return &logrSink{logger: l}
+}
+
+// derive returns a sink writing through l with the same caller skip as this sink.
+func (s *logrSink) derive(l *logger) *logrSink {
+	l.callerSkip = s.logger.callerSkip
+	return &logrSink{logger: l}
+}
@@ -0,0 +1,180 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+
+	"k8s.io/klog/v2"
+)
+
+func TestLogr(t *testing.T) {
+	s := RegisterScope("logr", "")
+
+	lines, err := captureStdout(func() {
+		o := testOptions()
+		o.LogCaller = true
+		if err := Configure(o); err != nil {
+			t.Errorf("Got err '%v', expecting success", err)
+		}
+
+		l := NewLogr(s)
+		l.Info("info", "replicas", 3)
+		l.V(1).Info("suppressed")
+		l.Error(errors.New("boom"), "error")
+
+		s.SetOutputLevel(DebugLevel)
+		l.WithValues("stack", "demo").V(2).Info("debug")
+
+		named := l.WithName("controller.deploy")
+		if FindScope("logr/controller_deploy") == nil {
+			t.Error("Expecting WithName to register the logr/controller_deploy scope")
+		}
+		named.V(1).Info("suppressed")
+		named.WithValues("request", "r1").Info("named")
+		_ = Sync()
+	})
+	if err != nil {
+		t.Fatalf("Got error '%v', expected success", err)
+	}
+
+	patterns := []string{
+		"\tinfo\tlogr\tlog/logr_test.go:.*\tinfo\t\\{\"replicas\": 3\\}$",
+		"\terror\tlogr\tlog/logr_test.go:.*\terror\t\\{\"error\": \"boom\"\\}$",
+		"\tdebug\tlogr\tlog/logr_test.go:.*\tdebug\t\\{\"stack\": \"demo\"\\}$",
+		"\tinfo\tlogr/controller_deploy\tlog/logr_test.go:.*\tnamed\t\\{\"request\": \"r1\"\\}$",
+		"",
+	}
+	if len(lines) != len(patterns) {
+		t.Fatalf("Got %d lines of output %v, expecting %d", len(lines), lines, len(patterns))
+	}
+	for i, pat := range patterns {
+		if match, _ := regexp.MatchString(pat, lines[i]); !match {
+			t.Errorf("Got '%s', expecting to match '%s'", lines[i], pat)
+		}
+	}
+}
+
+func TestLogrScopeLevels(t *testing.T) {
+	s := RegisterScope("logrlevels", "")
+
+	lines, err := captureStdout(func() {
+		o := testOptions()
+		o.OutputLevel = "info,logrlevels/worker:debug"
+		if err := Configure(o); err != nil {
+			t.Errorf("Got err '%v', expecting success", err)
+		}
+
+		// the scope is registered after configuring, and gets the level listed for it
+		l := NewLogr(s)
+		l.V(1).Info("suppressed")
+		l.WithName("worker").V(1).Info("worker-debug")
+		_ = Sync()
+		_ = Configure(testOptions())
+	})
+	if err != nil {
+		t.Fatalf("Got error '%v', expected success", err)
+	}
+
+	pattern := "\tdebug\tlogrlevels/worker\tworker-debug$"
+	if len(lines) != 2 {
+		t.Fatalf("Got %d lines of output %v, expecting 2", len(lines), lines)
+	}
+	if match, _ := regexp.MatchString(pattern, lines[0]); !match {
+		t.Errorf("Got '%s', expecting to match '%s'", lines[0], pattern)
+	}
+}
+
+func TestRedirectKlog(t *testing.T) {
+	s := RegisterScope("klog", "")
+
+	lines, err := captureStdout(func() {
+		o := testOptions()
+		o.LogCaller = true
+		if err := Configure(o); err != nil {
+			t.Errorf("Got err '%v', expecting success", err)
+		}
+
+		restore := RedirectKlog(s)
+		defer restore()
+
+		klog.Info("klog-info")
+		klog.V(4).Info("suppressed")
+		s.SetOutputLevel(DebugLevel)
+		klog.V(4).InfoS("klog-debug", "pod", "p1")
+		klog.ErrorS(errors.New("boom"), "klog-error")
+		_ = Sync()
+	})
+	if err != nil {
+		t.Fatalf("Got error '%v', expected success", err)
+	}
+
+	if klog.V(1).Enabled() {
+		t.Error("Got klog V-levels enabled, expecting the verbosity to be restored")
+	}
+
+	patterns := []string{
+		"\tinfo\tklog\tlog/logr_test.go:.*\tklog-info$",
+		"\tdebug\tklog\tlog/logr_test.go:.*\tklog-debug\t\\{\"pod\": \"p1\"\\}$",
+		"\terror\tklog\tlog/logr_test.go:.*\tklog-error\t\\{\"error\": \"boom\"\\}$",
+		"",
+	}
+	if len(lines) != len(patterns) {
+		t.Fatalf("Got %d lines of output %v, expecting %d", len(lines), lines, len(patterns))
+	}
+	for i, pat := range patterns {
+		if match, _ := regexp.MatchString(pat, lines[i]); !match {
+			t.Errorf("Got '%s', expecting to match '%s'", lines[i], pat)
+		}
+	}
+}
+
+func TestLogrTopLevelScopeLevels(t *testing.T) {
+	defer func() { _ = Configure(testOptions()) }()
+
+	o := testOptions()
+	o.OutputLevel = "warn,logr-runtime:debug"
+	if err := Configure(o); err != nil {
+		t.Fatalf("Got err '%v', expecting success", err)
+	}
+
+	// scopes named by logr loggers of the default scope are top-level scopes
+	NewLogr(defaultScope).WithName("logr-runtime")
+	if got := FindScope("logr-runtime").GetOutputLevel(); got != DebugLevel {
+		t.Errorf("Got %v, expecting the level listed for the scope", got)
+	}
+}
+
+func TestLateScopeCatchAllLevel(t *testing.T) {
+	defer func() { _ = Configure(testOptions()) }()
+
+	o := testOptions()
+	o.OutputLevel = "debug,default:warn"
+	o.StackTraceLevel = "error,default:none"
+	if err := Configure(o); err != nil {
+		t.Fatalf("Got err '%v', expecting success", err)
+	}
+
+	// the scope gets the level of every unlisted scope, not the one of the default scope
+	s := RegisterScope("latecatchall", "")
+	if got := s.GetOutputLevel(); got != DebugLevel {
+		t.Errorf("Got output level %v, expecting %v", got, DebugLevel)
+	}
+	if got := s.GetStackTraceLevel(); got != ErrorLevel {
+		t.Errorf("Got stack trace level %v, expecting %v", got, ErrorLevel)
+	}
+}
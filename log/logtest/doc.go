@@ -0,0 +1,30 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package logtest records the entries written through the log package in memory, so
+// that unit tests can assert on them:
+//
+//	func TestApply(t *testing.T) {
+//		logs := logtest.NewT(t)
+//
+//		apply()
+//
+//		if warnings := logs.Entries().FilterLevel(log.WarnLevel); len(warnings) != 0 {
+//			t.Errorf("Got warnings %v", warnings.Messages())
+//		}
+//	}
+//
+// Recording configures the global logger, so tests recording entries must not run in
+// parallel.
+package logtest
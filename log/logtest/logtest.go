@@ -0,0 +1,288 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logtest
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+
+	"kusionstack.io/component-base/log"
+)
+
+// Entry is a recorded log entry.
+type Entry struct {
+	Level   log.Level
+	Time    time.Time
+	Scope   string
+	Message string
+	// Fields are the fields of the entry, as encoded by a zapcore.MapObjectEncoder.
+	// Integers are recorded as int64, and errors as their message.
+	Fields map[string]any
+	// Caller is the caller of the logging function, if callers are logged.
+	Caller zapcore.EntryCaller
+	Stack  string
+}
+
+// Entries is a list of recorded entries, in the order they were logged.
+type Entries []Entry
+
+// Filter returns the entries for which keep returns true.
+func (es Entries) Filter(keep func(e Entry) bool) Entries {
+	var filtered Entries
+	for _, e := range es {
+		if keep(e) {
+			filtered = append(filtered, e)
+		}
+	}
+	return filtered
+}
+
+// FilterLevel returns the entries logged at the level.
+func (es Entries) FilterLevel(level log.Level) Entries {
+	return es.Filter(func(e Entry) bool { return e.Level == level })
+}
+
+// FilterScope returns the entries logged by the named scope. Entries logged through the
+// package-level functions are logged by the default scope.
+func (es Entries) FilterScope(name string) Entries {
+	return es.Filter(func(e Entry) bool { return e.Scope == name })
+}
+
+// FilterMessage returns the entries with the message.
+func (es Entries) FilterMessage(msg string) Entries {
+	return es.Filter(func(e Entry) bool { return e.Message == msg })
+}
+
+// FilterMessageContains returns the entries whose message contains substr.
+func (es Entries) FilterMessageContains(substr string) Entries {
+	return es.Filter(func(e Entry) bool { return strings.Contains(e.Message, substr) })
+}
+
+// FilterField returns the entries with the field set to the value. Values are compared
+// by their default formatting, so that 3 matches a field recorded as an int64.
+func (es Entries) FilterField(key string, value any) Entries {
+	expected := fmt.Sprint(value)
+	return es.Filter(func(e Entry) bool {
+		v, ok := e.Fields[key]
+		return ok && fmt.Sprint(v) == expected
+	})
+}
+
+// FilterFieldKey returns the entries with the field, whatever its value.
+func (es Entries) FilterFieldKey(key string) Entries {
+	return es.Filter(func(e Entry) bool {
+		_, ok := e.Fields[key]
+		return ok
+	})
+}
+
+// Messages returns the messages of the entries.
+func (es Entries) Messages() []string {
+	msgs := make([]string, len(es))
+	for i, e := range es {
+		msgs[i] = e.Message
+	}
+	return msgs
+}
+
+// Recorder records the entries written through the log package.
+type Recorder struct {
+	// previous are the options the logger is configured with again by Stop.
+	previous *log.Options
+
+	mu      sync.Mutex
+	entries Entries
+}
+
+// New configures the logger to record every entry in memory, at all levels and with
+// callers, until Stop is called. Fatal entries still exit the process.
+func New() *Recorder {
+	r := &Recorder{previous: log.CurrentOptions()}
+	r.configure(nil)
+	return r
+}
+
+// NewT is like New, and also writes the entries to t.Log, so that they are shown for
+// failed tests or with go test -v. The logger is restored once the test completes.
+func NewT(t testing.TB) *Recorder {
+	t.Helper()
+
+	r := &Recorder{previous: log.CurrentOptions()}
+	w := &tbWriter{t: t}
+	r.configure(zapcore.NewCore(zapcore.NewConsoleEncoder(zapcore.EncoderConfig{
+		LevelKey:       "level",
+		NameKey:        "scope",
+		CallerKey:      "caller",
+		MessageKey:     "msg",
+		StacktraceKey:  "stack",
+		LineEnding:     zapcore.DefaultLineEnding,
+		EncodeLevel:    zapcore.LowercaseLevelEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+		EncodeDuration: zapcore.StringDurationEncoder,
+	}), zapcore.AddSync(w), zapcore.DebugLevel))
+	t.Cleanup(func() {
+		w.stop()
+		r.Stop()
+	})
+	return r
+}
+
+func (r *Recorder) configure(tbCore zapcore.Core) {
+	o := log.DefaultOptions()
+	o.OutputPath = ""
+	o.OutputLevel = "debug"
+	o.LogCaller = true
+	o.Sinks = []log.SinkOptions{{Core: &recordingCore{recorder: r}}}
+	if tbCore != nil {
+		o.Sinks = append(o.Sinks, log.SinkOptions{Core: tbCore})
+	}
+
+	if err := log.Configure(o); err != nil {
+		panic(fmt.Sprintf("failed to configure the logger to record entries: %v", err))
+	}
+}
+
+// Stop stops recording entries, and configures the logger again with the options it
+// was configured with before the recorder was created.
+func (r *Recorder) Stop() {
+	_ = log.Configure(r.previous)
+}
+
+// Entries returns the entries recorded so far.
+func (r *Recorder) Entries() Entries {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append(Entries(nil), r.entries...)
+}
+
+// Len returns the number of entries recorded so far.
+func (r *Recorder) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.entries)
+}
+
+// Reset forgets the entries recorded so far.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries = nil
+}
+
+func (r *Recorder) record(ent zapcore.Entry, fields []zapcore.Field) {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+
+	scope := ent.LoggerName
+	if scope == "" {
+		scope = log.DefaultLoggerName
+	}
+
+	e := Entry{
+		Level:   toLevel(ent.Level),
+		Time:    ent.Time,
+		Scope:   scope,
+		Message: ent.Message,
+		Fields:  enc.Fields,
+		Caller:  ent.Caller,
+		Stack:   ent.Stack,
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries = append(r.entries, e)
+}
+
+func toLevel(level zapcore.Level) log.Level {
+	switch level {
+	case zapcore.DebugLevel:
+		return log.DebugLevel
+	case zapcore.InfoLevel:
+		return log.InfoLevel
+	case zapcore.WarnLevel:
+		return log.WarnLevel
+	case zapcore.ErrorLevel:
+		return log.ErrorLevel
+	}
+	return log.FatalLevel
+}
+
+// recordingCore records every entry written to it.
+type recordingCore struct {
+	recorder *Recorder
+	fields   []zapcore.Field
+}
+
+func (c *recordingCore) Enabled(zapcore.Level) bool {
+	return true
+}
+
+func (c *recordingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &recordingCore{
+		recorder: c.recorder,
+		fields:   append(c.fields[:len(c.fields):len(c.fields)], fields...),
+	}
+}
+
+func (c *recordingCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return ce.AddCore(ent, c)
+}
+
+func (c *recordingCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	c.recorder.record(ent, append(c.fields[:len(c.fields):len(c.fields)], fields...))
+	return nil
+}
+
+func (c *recordingCore) Sync() error {
+	return nil
+}
+
+// tbWriter writes each encoded entry to t.Log, until stopped. Entries written once the
+// test completes, such as by goroutines still running, are dropped as t.Log panics.
+type tbWriter struct {
+	t testing.TB
+
+	mu      sync.Mutex
+	stopped bool
+}
+
+func (w *tbWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.stopped {
+		w.t.Helper()
+		w.t.Log(strings.TrimSuffix(string(p), "\n"))
+	}
+	return len(p), nil
+}
+
+func (w *tbWriter) stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.stopped = true
+}
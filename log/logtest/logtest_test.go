@@ -0,0 +1,147 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logtest
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"kusionstack.io/component-base/log"
+)
+
+func TestRecorder(t *testing.T) {
+	engineLog := log.RegisterScope("engine", "")
+
+	r := New()
+	defer r.Stop()
+
+	log.Info("starting")
+	engineLog.With("resource", "web").Debugf("applying %d resources", 3)
+	engineLog.ErrorS(errors.New("boom"), "failed", "attempt", 2)
+	zap.L().Warn("through zap", zap.Bool("zap", true))
+
+	entries := r.Entries()
+	if got := entries.Messages(); !reflect.DeepEqual(got, []string{"starting", "applying 3 resources", "failed", "through zap"}) {
+		t.Fatalf("Got messages %q, expected every entry to be recorded", got)
+	}
+
+	first := entries[0]
+	if first.Level != log.InfoLevel || first.Scope != log.DefaultLoggerName || !strings.HasSuffix(first.Caller.File, "logtest_test.go") {
+		t.Errorf("Got %+v, expected an info entry of the default scope logged by this test", first)
+	}
+
+	engine := entries.FilterScope("engine")
+	if len(engine) != 2 {
+		t.Fatalf("Got %d entries of the engine scope, expected 2", len(engine))
+	}
+	if got := engine.FilterLevel(log.DebugLevel).FilterField("resource", "web").Messages(); !reflect.DeepEqual(got, []string{"applying 3 resources"}) {
+		t.Errorf("Got %q, expected the debug entry with the scope field", got)
+	}
+	if got := engine.FilterField("attempt", 2); len(got) != 1 || got[0].Fields["error"] != "boom" {
+		t.Errorf("Got %+v, expected the error entry with its fields", got)
+	}
+
+	if got := entries.FilterMessageContains("zap").FilterFieldKey("zap"); len(got) != 1 || got[0].Level != log.WarnLevel {
+		t.Errorf("Got %+v, expected the warning logged through zap", got)
+	}
+	if got := entries.FilterMessage("missing"); len(got) != 0 {
+		t.Errorf("Got %+v, expected no entries", got)
+	}
+
+	r.Reset()
+	if r.Len() != 0 {
+		t.Errorf("Got %d entries after resetting, expected none", r.Len())
+	}
+}
+
+func TestStopRestores(t *testing.T) {
+	o := log.DefaultOptions()
+	o.OutputLevel = "warn"
+	o.LogCaller = true
+	if err := log.Configure(o); err != nil {
+		t.Fatalf("Got error '%v', expected success", err)
+	}
+	defer func() { _ = log.Configure(log.DefaultOptions()) }()
+
+	r := New()
+	r.Stop()
+
+	if got := log.CurrentOptions(); got.OutputLevel != "warn" || !got.LogCaller || len(got.Sinks) != 0 {
+		t.Errorf("Got options %+v, expected the options before recording to be restored", got)
+	}
+	if got := log.FindScope(log.DefaultLoggerName).GetOutputLevel(); got != log.WarnLevel {
+		t.Errorf("Got output level %v, expected %v", got, log.WarnLevel)
+	}
+}
+
+// fakeTB records the lines logged by a test.
+type fakeTB struct {
+	testing.TB
+	lines    []string
+	cleanups []func()
+}
+
+func (tb *fakeTB) Helper() {}
+
+func (tb *fakeTB) Log(args ...any) {
+	for _, arg := range args {
+		tb.lines = append(tb.lines, arg.(string))
+	}
+}
+
+func (tb *fakeTB) Cleanup(f func()) {
+	tb.cleanups = append(tb.cleanups, f)
+}
+
+func TestNewT(t *testing.T) {
+	tb := &fakeTB{TB: t}
+	r := NewT(tb)
+
+	log.Warn("logged to the test")
+	if r.Len() != 1 {
+		t.Errorf("Got %d entries, expected the entry to be recorded", r.Len())
+	}
+	if len(tb.lines) != 1 || !strings.HasPrefix(tb.lines[0], "warn\t") || !strings.HasSuffix(tb.lines[0], "\tlogged to the test") {
+		t.Errorf("Got %q, expected the entry to be logged to the test", tb.lines)
+	}
+
+	// the logger is restored once the test completes
+	for _, f := range tb.cleanups {
+		f()
+	}
+	log.Warn("not recorded")
+	if r.Len() != 1 || len(tb.lines) != 1 {
+		t.Errorf("Got %d entries and %d lines, expected the entry not to be recorded", r.Len(), len(tb.lines))
+	}
+}
+
+func TestTBWriterStopped(t *testing.T) {
+	tb := &fakeTB{TB: t}
+	w := &tbWriter{t: tb}
+
+	_, _ = w.Write([]byte("logged\n"))
+	w.stop()
+	if n, err := w.Write([]byte("dropped\n")); n != len("dropped\n") || err != nil {
+		t.Errorf("Got %d, %v, expected the write to succeed", n, err)
+	}
+
+	if len(tb.lines) != 1 || tb.lines[0] != "logged" {
+		t.Errorf("Got %q, expected the entry written once stopped to be dropped", tb.lines)
+	}
+}
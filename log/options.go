@@ -15,21 +15,50 @@
 package log
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/spf13/pflag"
 	"go.uber.org/zap/zapcore"
+	"sigs.k8s.io/yaml"
 )
 
 const (
 	DefaultLoggerName         = "default"
+	DefaultEnvPrefix          = "KUSION"
 	DefaultOutputLevel        = InfoLevel
 	DefaultStackTraceLevel    = NoneLevel
 	DefaultOutputPath         = "stdout"
 	DefaultErrorOutputPath    = "stderr"
 	DefaultRotationMaxAge     = 30
-	DefaultRotationMaxSize    = 100 * 1024 * 1024
+	DefaultRotationMaxSize    = 100 * Mebibyte
 	DefaultRotationMaxBackups = 1000
+
+	// DefaultRotationBackupFormat names backups after the log file, with the time they
+	// were rotated inserted before the extension, such as app-2024-05-01T10-00-00.000.log.
+	DefaultRotationBackupFormat = "{name}-2006-01-02T15-04-05.000{ext}"
+
+	DefaultAsyncQueueSize     = 1024
+	DefaultAsyncBufferSize    = 256 * Kibibyte
+	DefaultAsyncFlushInterval = "1s"
+	DefaultAsyncOverflow      = OverflowBlock
+
+	DefaultSamplingInterval = "1s"
+
+	DefaultFormat = ConsoleEncoding
+
+	DefaultColor            = ColorAuto
+	DefaultPrettyTimestamps = TimestampsLocal
+
+	DefaultTimeFormat   = TimeFormatRFC3339Micro
+	DefaultTimeZone     = "UTC"
+	DefaultLevelCase    = LevelCaseLower
+	DefaultCallerFormat = CallerShort
 )
 
 // Level is an enumeration of all supported log levels.
@@ -87,97 +116,731 @@ var levelToZap = map[Level]zapcore.Level{
 var levelListString = []string{"debug", "info", "warn", "error", "fatal", "none"}
 
 // Options defines the set of options supported by component-base logging package.
+//
+// Options can also be loaded from a YAML or JSON configuration file using the field
+// names given by the json struct tags, see Complete.
 type Options struct {
+	// ConfigFile is the path to a YAML or JSON file to load the options from.
+	// Values set explicitly by flags take precedence over the file.
+	ConfigFile string `json:"-"`
+
+	// EnvPrefix is the prefix of the environment variables the options can be set
+	// from, see Complete. This defaults to KUSION. An empty prefix disables
+	// environment variables.
+	EnvPrefix string `json:"-"`
+
 	// OutputPath is a file system path to write the log data to.
 	// The special values stdout and stderr can be used to output to the
-	// standard I/O streams. This defaults to stdout.
-	OutputPath string
+	// standard I/O streams. URLs with a scheme registered by RegisterSink
+	// write to custom destinations. This defaults to stdout.
+	OutputPath string `json:"outputPath"`
 
-	// ErrorOutputPath is a file system path to write logger errors to.
-	// The special values stdout and stderr can be used to output to the
-	// standard I/O streams. This defaults to stderr.
-	ErrorOutputPath string
+	// ErrorOutputPath is a file system path to write logger errors to, such as
+	// failures to write log entries to the outputs. The special values stdout and
+	// stderr can be used to output to the standard I/O streams, and URLs with a scheme
+	// registered by RegisterSink write to custom destinations. This defaults to stderr.
+	ErrorOutputPath string `json:"errorOutputPath"`
 
-	// RotateOutputPath is the path to a rotating log file. This file should
-	// be automatically rotated over time, based on the rotation parameters such
-	// as RotationMaxSize and RotationMaxAge. The default is to not rotate.
+	// RotateOutputPath is the path to a rotating log file. This file is
+	// automatically rotated once it reaches RotationMaxSizeBytes, and at every
+	// boundary of the RotationInterval if set. The default is to not rotate.
 	//
 	// This path is used as a foundational path. This is where log output is normally
-	// saved. When a rotation needs to take place because the file got too big or too
-	// old, then the file is renamed by appending a timestamp to the name. Such renamed
-	// files are called backups. Once a backup has been created,
-	// output resumes to this path.
-	RotateOutputPath string
+	// saved. When a rotation needs to take place because the file got too big or a
+	// rotation boundary was crossed, then the file is renamed according to
+	// RotationBackupFormat. Such renamed files are called backups. Once a backup has
+	// been created, output resumes to this path.
+	RotateOutputPath string `json:"rotateOutputPath"`
 
-	// RotationMaxSize is the maximum size in megabytes of a log file before it gets
-	// rotated. It defaults to 100 megabytes.
-	RotationMaxSize int
+	// RotationMaxSizeBytes is the maximum size in bytes of a log file before it gets
+	// rotated, such as 100 * Mebibyte. In flags and config files, it is given with a unit,
+	// such as "100Mi" or "1G". Zero disables rotation by size. It defaults to 100
+	// mebibytes. It replaces RotationMaxSize, which was a number of megabytes.
+	RotationMaxSizeBytes Size `json:"rotationMaxSize"`
 
 	// RotationMaxAge is the maximum number of days to retain old log files based on the
 	// timestamp encoded in their filename. Note that a day is defined as 24
 	// hours and may not exactly correspond to calendar days due to daylight
 	// savings, leap seconds, etc. The default is to remove log files
 	// older than 30 days.
-	RotationMaxAge int
+	RotationMaxAge int `json:"rotationMaxAge"`
 
 	// RotationMaxBackups is the maximum number of old log files to retain.  The default
 	// is to retain at most 1000 logs.
-	RotationMaxBackups int
+	RotationMaxBackups int `json:"rotationMaxBackups"`
+
+	// RotationInterval is the interval at which the rotating log file is rotated
+	// regardless of its size, either hourly or daily. The default is to only rotate
+	// based on RotationMaxSizeBytes.
+	RotationInterval string `json:"rotationInterval"`
+
+	// RotationOffset is the duration after the start of each hour or day at which
+	// the rotating log file is rotated, such as 2h to rotate daily at 02:00. It
+	// defaults to rotating at the start of each interval.
+	RotationOffset string `json:"rotationOffset"`
+
+	// RotationTimeZone is the IANA name of the time zone, such as UTC or Asia/Shanghai,
+	// in which rotation boundaries are computed and backups are named. It defaults to
+	// the local time zone.
+	RotationTimeZone string `json:"rotationTimeZone"`
+
+	// RotationBackupFormat is the pattern naming backups, in which {name} and {ext}
+	// stand for the name of the log file without and with only its extension, and a
+	// Go time layout for the time of the backup. The placeholders must come before
+	// or after the time layout. With RotationInterval set, backups are named after the
+	// start of the interval they cover, so that daily backups can be named after their
+	// day with {name}-2006-01-02{ext}. Otherwise, they are named after the time they
+	// were rotated. Backups sharing a name get a numbered suffix, such as
+	// app-2024-05-01.1.log. It defaults to DefaultRotationBackupFormat.
+	RotationBackupFormat string `json:"rotationBackupFormat"`
+
+	// RotationCompress is the method compressing backups in the background after each
+	// rotation, either gzip or zstd. Compressed backups get a .gz or .zst extension,
+	// and count towards RotationMaxBackups and RotationMaxAge like the others. The
+	// default is to keep backups uncompressed.
+	RotationCompress string `json:"rotationCompress"`
+
+	// Sinks are additional outputs, each with its own encoding and minimum level,
+	// written to alongside OutputPath and RotateOutputPath.
+	Sinks []SinkOptions `json:"sinks"`
+
+	// Async controls whether entries are written to OutputPath, RotateOutputPath and Sinks
+	// in the background rather than by the logging call. Each output gets a queue of
+	// entries, whose content is written through a buffer flushed at regular intervals
+	// and by Sync. Errors writing in the background are reported to ErrorOutputPath and
+	// counted by WriteErrors. Entries still queued when the process exits without calling
+	// Sync or Close are lost.
+	Async bool `json:"async"`
+
+	// AsyncQueueSize is the maximum number of entries queued for each output. It defaults
+	// to 1024.
+	AsyncQueueSize int `json:"asyncQueueSize"`
+
+	// AsyncBufferSize is the size in bytes of the buffer of each output, given with a
+	// unit in flags and config files, such as "256Ki". Entries
+	// are buffered whole, and entries larger than the buffer are written directly. Zero
+	// disables buffering. It defaults to 256 kibibytes.
+	AsyncBufferSize Size `json:"asyncBufferSize"`
+
+	// AsyncFlushInterval is the interval at which buffers are flushed, such as "500ms".
+	// It defaults to one second.
+	AsyncFlushInterval string `json:"asyncFlushInterval"`
+
+	// AsyncOverflow is the policy applied when logging to a full queue, one of block,
+	// drop_oldest and drop_newest. Dropped entries are counted by DroppedEntries. It
+	// defaults to block.
+	AsyncOverflow string `json:"asyncOverflow"`
 
-	// JSONEncoding controls whether the log is formatted as JSON.
-	JSONEncoding bool
+	// Format is the format of the log written to OutputPath and RotateOutputPath, one of
+	// console, json, pretty, logfmt, ecs and otel. The ecs and otel formats are JSON
+	// following the Elastic Common Schema and the OpenTelemetry log data model. It
+	// defaults to console.
+	Format string `json:"format"`
 
-	// OutputLevel controls the log level.
-	OutputLevel string
+	// JSONEncoding controls whether the log is formatted as JSON. It is kept for
+	// compatibility, and is the same as setting Format to json.
+	JSONEncoding bool `json:"jsonEncoding"`
 
-	// StackTraceLevel controls the log level for stack trace.
-	StackTraceLevel string
+	// PrettyEncoding controls whether the log is formatted for people reading it in a
+	// terminal, with colored levels, aligned scopes and fields on their own lines. It
+	// is the same as setting Format to pretty.
+	PrettyEncoding bool `json:"prettyEncoding"`
+
+	// Color controls whether pretty output is colored, one of auto, always and never.
+	// With auto, output is only colored when written to stdout or stderr attached to a
+	// terminal, and the NO_COLOR environment variable isn't set. It defaults to auto.
+	Color string `json:"color"`
+
+	// PrettyTimestamps controls the timestamps of pretty output, either local for the
+	// local time of day or relative for the time elapsed since the process started. It
+	// defaults to local.
+	PrettyTimestamps string `json:"prettyTimestamps"`
+
+	// TimeFormat is the format of the time of entries, one of rfc3339micro, rfc3339,
+	// rfc3339nano and epoch_millis, or a custom Go time layout such as
+	// "2006-01-02 15:04:05.000". It defaults to rfc3339micro. The time format, time
+	// zone, keys, level case and caller format only apply to the console, json and
+	// logfmt formats, as the pretty, ecs and otel formats have fixed layouts.
+	TimeFormat string `json:"timeFormat"`
+
+	// TimeZone is the IANA name of the time zone the time of entries is written in,
+	// such as Asia/Shanghai, or Local for the local time zone. It defaults to UTC.
+	TimeZone string `json:"timeZone"`
+
+	// EncoderKeys renames the keys of entries. It is a comma-separated list in the form
+	// <key>:<name>,<key>:<name>,... where key is one of time, level, scope, caller, msg
+	// and stack, such as time:@timestamp,msg:message. An empty name leaves the key out.
+	EncoderKeys string `json:"encoderKeys"`
+
+	// LevelCase controls whether levels are written in lower or upper case, such as
+	// info or INFO. It defaults to lower.
+	LevelCase string `json:"levelCase"`
+
+	// CallerFormat controls whether callers are written as their package directory and
+	// file name, or as the full path of the file, either short or full. It defaults to
+	// short.
+	CallerFormat string `json:"callerFormat"`
+
+	// OutputLevel controls the log level. It is a comma-separated list of levels in the
+	// form <scope>:<level>,<scope>:<level>,... A level without a scope applies to every
+	// scope that isn't listed explicitly. Scopes can be listed before they are registered,
+	// such as the scopes of logr loggers, which are registered lazily.
+	OutputLevel string `json:"outputLevel"`
+
+	// StackTraceLevel controls the log level for stack trace. It uses the same per-scope
+	// syntax as OutputLevel.
+	StackTraceLevel string `json:"stackTraceLevel"`
+
+	// Sampling limits the number of similar entries written per SamplingInterval. It is
+	// a comma-separated list in the form <scope>:<first>/<thereafter>,... to write the
+	// first entries with the same level and message, then every thereafter-th one.
+	// none disables sampling. A sampling without a scope applies to every scope that
+	// isn't listed explicitly. The default is to write every entry.
+	Sampling string `json:"sampling"`
+
+	// SamplingInterval is the interval over which entries are sampled, such as "1s".
+	// It defaults to one second.
+	SamplingInterval string `json:"samplingInterval"`
 
 	// LogCaller controls whether to log the caller of a logging function
-	LogCaller bool
+	LogCaller bool `json:"logCaller"`
+
+	// CaptureSlog controls whether Configure installs a handler writing through the default
+	// scope as the log/slog default logger. A later Configure without it restores the
+	// default logger in place before.
+	CaptureSlog bool `json:"captureSlog"`
+
+	// flags is the flag set the options were added to by AddFlags.
+	flags *pflag.FlagSet
+	// completed records whether Complete was called.
+	completed bool
 }
 
 // DefaultOptions returns a new set of options, initialized to the defaults
 func DefaultOptions() *Options {
 	return &Options{
-		OutputPath:         DefaultOutputPath,
-		ErrorOutputPath:    DefaultErrorOutputPath,
-		RotationMaxSize:    DefaultRotationMaxSize,
-		RotationMaxAge:     DefaultRotationMaxAge,
-		RotationMaxBackups: DefaultRotationMaxBackups,
-		OutputLevel:        levelToString[InfoLevel],
-		StackTraceLevel:    levelToString[NoneLevel],
-		LogCaller:          false,
+		EnvPrefix:            DefaultEnvPrefix,
+		OutputPath:           DefaultOutputPath,
+		ErrorOutputPath:      DefaultErrorOutputPath,
+		RotationMaxSizeBytes: DefaultRotationMaxSize,
+		RotationMaxAge:       DefaultRotationMaxAge,
+		RotationMaxBackups:   DefaultRotationMaxBackups,
+		RotationBackupFormat: DefaultRotationBackupFormat,
+		AsyncQueueSize:       DefaultAsyncQueueSize,
+		AsyncBufferSize:      DefaultAsyncBufferSize,
+		AsyncFlushInterval:   DefaultAsyncFlushInterval,
+		AsyncOverflow:        DefaultAsyncOverflow,
+		SamplingInterval:     DefaultSamplingInterval,
+		Format:               DefaultFormat,
+		Color:                DefaultColor,
+		PrettyTimestamps:     DefaultPrettyTimestamps,
+		TimeFormat:           DefaultTimeFormat,
+		TimeZone:             DefaultTimeZone,
+		LevelCase:            DefaultLevelCase,
+		CallerFormat:         DefaultCallerFormat,
+		OutputLevel:          levelToString[InfoLevel],
+		StackTraceLevel:      levelToString[NoneLevel],
+		LogCaller:            false,
 	}
 }
 
 // AddFlags add logging-format flag.
+//
+// The help text of every flag names the environment variable that can set it too,
+// see Complete.
+//
+// Configure must be called after parsing the flags. It applies the environment variables
+// and the file set by --log_config, with Complete, unless Complete was already called,
+// such as to validate the options from a cobra PreRunE hook.
 func (o *Options) AddFlags(fs *pflag.FlagSet) {
+	o.flags = fs
+	ofs := o.flagSet()
+	if o.EnvPrefix != "" {
+		ofs.VisitAll(func(f *pflag.Flag) {
+			f.Usage += fmt.Sprintf(" (env %s)", o.envName(f.Name))
+		})
+	}
+	fs.AddFlagSet(ofs)
+}
+
+// flagSet returns a new flag set with flags for all options, bound to o.
+func (o *Options) flagSet() *pflag.FlagSet {
+	fs := pflag.NewFlagSet("log", pflag.ContinueOnError)
+
+	fs.StringVar(&o.ConfigFile, "log_config", o.ConfigFile,
+		"The path to a YAML or JSON file to load the logging options from. Flags set explicitly take precedence")
+
 	fs.StringVar(&o.OutputPath, "log_path", o.OutputPath,
-		"The file path where to output the log. This can be any path as well as the special values stdout and stderr")
+		"The file path where to output the log. This can be any path as well as the special values stdout and stderr, "+
+			"or a URL with a scheme registered as a custom sink")
+
+	fs.StringVar(&o.ErrorOutputPath, "log_error_path", o.ErrorOutputPath,
+		"The file path where to output errors of the logger itself, such as failures to write log entries. "+
+			"This can be any path as well as the special values stdout and stderr, or a URL with a scheme registered as a custom sink")
 
 	fs.StringVar(&o.RotateOutputPath, "log_rotate_path", o.RotateOutputPath,
 		"The file path for the optional rotating log file")
 
 	fs.IntVar(&o.RotationMaxAge, "log_rotate_max_age", o.RotationMaxAge,
-		"The maximum age in days of a log file beyond which the file is rotated (0 indicates no limit)")
+		"The maximum age in days of log file backups to keep before they are deleted (0 indicates no limit)")
 
-	fs.IntVar(&o.RotationMaxSize, "log_rotate_max_size", o.RotationMaxSize,
-		"The maximum size in megabytes of a log file beyond which the file is rotated")
+	fs.Var(&o.RotationMaxSizeBytes, "log_rotate_max_size",
+		"The maximum size of a log file beyond which the file is rotated, such as 100Mi or 1G. "+
+			"0 disables rotation by size")
 
 	fs.IntVar(&o.RotationMaxBackups, "log_rotate_max_backups", o.RotationMaxBackups,
 		"The maximum number of log file backups to keep before older files are deleted (0 indicates no limit)")
 
+	fs.StringVar(&o.RotationInterval, "log_rotate_interval", o.RotationInterval,
+		fmt.Sprintf("The interval at which the rotating log file is rotated regardless of its size, one of %s. "+
+			"The file is only rotated by size if empty", rotationIntervalListString))
+
+	fs.StringVar(&o.RotationOffset, "log_rotate_offset", o.RotationOffset,
+		"The duration after the start of each rotation interval at which the file is rotated, such as 2h to rotate daily at 02:00")
+
+	fs.StringVar(&o.RotationTimeZone, "log_rotate_timezone", o.RotationTimeZone,
+		"The time zone of rotation boundaries and backup names, such as UTC or Asia/Shanghai. Defaults to the local time zone")
+
+	fs.StringVar(&o.RotationBackupFormat, "log_rotate_backup_format", o.RotationBackupFormat,
+		"The pattern naming log file backups, made of a Go time layout preceded or followed by the {name} and {ext} "+
+			"placeholders for the name and the extension of the log file")
+
+	fs.StringVar(&o.RotationCompress, "log_rotate_compress", o.RotationCompress,
+		fmt.Sprintf("The method compressing log file backups, one of %s. Backups are compressed with gzip if the flag "+
+			"is given without a value, and are kept uncompressed if it isn't given", compressionListString))
+	fs.Lookup("log_rotate_compress").NoOptDefVal = CompressGzip
+
+	fs.BoolVar(&o.Async, "log_async", o.Async,
+		"Whether to write log entries to the outputs in the background, buffered, rather than in the logging call")
+
+	fs.IntVar(&o.AsyncQueueSize, "log_async_queue_size", o.AsyncQueueSize,
+		"The maximum number of log entries queued for each output when writing in the background")
+
+	fs.Var(&o.AsyncBufferSize, "log_async_buffer_size",
+		"The size of the buffer of each output when writing in the background, such as 256Ki")
+
+	fs.StringVar(&o.AsyncFlushInterval, "log_async_flush_interval", o.AsyncFlushInterval,
+		"The interval at which buffered log entries are flushed when writing in the background, such as 500ms")
+
+	fs.StringVar(&o.AsyncOverflow, "log_async_overflow", o.AsyncOverflow,
+		fmt.Sprintf("What to do when logging to a full queue when writing in the background, one of %s", overflowListString))
+
+	fs.StringVar(&o.Format, "log_format", o.Format,
+		fmt.Sprintf("The format of the output, one of %s. The ecs and otel formats are JSON following the "+
+			"Elastic Common Schema and the OpenTelemetry log data model", encodingListString))
+
 	fs.BoolVar(&o.JSONEncoding, "log_as_json", o.JSONEncoding,
-		"Whether to format output as JSON or in plain console-friendly format")
+		"Whether to format output as JSON, the same as --log_format json")
+
+	fs.BoolVar(&o.PrettyEncoding, "log_pretty", o.PrettyEncoding,
+		"Whether to format output for people reading it in a terminal, the same as --log_format pretty")
+
+	fs.StringVar(&o.Color, "log_color", o.Color,
+		fmt.Sprintf("Whether to color pretty output, one of %s. With auto, output is colored when written to "+
+			"a terminal and the NO_COLOR environment variable isn't set", colorListString))
+
+	fs.StringVar(&o.PrettyTimestamps, "log_pretty_timestamps", o.PrettyTimestamps,
+		fmt.Sprintf("The timestamps of pretty output, one of %s. Relative timestamps are the time elapsed since "+
+			"the process started", timestampsListString))
+
+	fs.StringVar(&o.TimeFormat, "log_time_format", o.TimeFormat,
+		fmt.Sprintf("The format of the time of log entries, one of %s, or a custom Go time layout such as "+
+			"'2006-01-02 15:04:05.000'", timeFormatListString))
+
+	fs.StringVar(&o.TimeZone, "log_timezone", o.TimeZone,
+		"The time zone the time of log entries is written in, such as UTC, Local or Asia/Shanghai")
+
+	fs.StringVar(&o.EncoderKeys, "log_keys", o.EncoderKeys,
+		fmt.Sprintf("Comma-separated names of the keys of log entries, in the form of <key>:<name>,<key>:<name>,... "+
+			"where key can be one of %s. An empty name leaves the key out", encoderKeys))
+
+	fs.StringVar(&o.LevelCase, "log_level_case", o.LevelCase,
+		fmt.Sprintf("The case of the level of log entries, one of %s", levelCaseListString))
+
+	fs.StringVar(&o.CallerFormat, "log_caller_format", o.CallerFormat,
+		fmt.Sprintf("The format of the caller of log entries, one of %s. Short callers are made of the package "+
+			"directory and file name, and full callers of the full path of the file", callerFormatListString))
 
 	fs.StringVar(&o.OutputLevel, "log_output_level", o.OutputLevel,
-		fmt.Sprintf("The minimum logging level of messages to output,  can be one of %s",
-			levelListString))
+		fmt.Sprintf("Comma-separated minimum per-scope logging level of messages to output, in the form of "+
+			"<scope>:<level>,<scope>:<level>,... where scope can be one of %s and level can be one of %s. "+
+			"A level without a scope applies to all scopes",
+			scopeNames(), levelListString))
 
 	fs.StringVar(&o.StackTraceLevel, "log_stacktrace_level", o.StackTraceLevel,
-		fmt.Sprintf("The minimum logging level at which stack traces are captured, can be one of %s",
-			levelListString))
+		fmt.Sprintf("Comma-separated minimum per-scope logging level at which stack traces are captured, in the form of "+
+			"<scope>:<level>,<scope>:<level>,... where scope can be one of %s and level can be one of %s. "+
+			"A level without a scope applies to all scopes",
+			scopeNames(), levelListString))
+
+	fs.StringVar(&o.Sampling, "log_sampling", o.Sampling,
+		fmt.Sprintf("Comma-separated per-scope sampling of similar log entries, in the form of "+
+			"<scope>:<first>/<thereafter>,... to write the first entries with the same level and message in every "+
+			"sampling interval, then every thereafter-th one. Scope can be one of %s, and none disables sampling. "+
+			"A sampling without a scope applies to all scopes", scopeNames()))
+
+	fs.StringVar(&o.SamplingInterval, "log_sampling_interval", o.SamplingInterval,
+		"The interval over which similar log entries are sampled, such as 1s")
 
 	fs.BoolVar(&o.LogCaller, "log_caller", o.LogCaller, "Whether to log the caller of a logging function or not")
+
+	fs.BoolVar(&o.CaptureSlog, "log_capture_slog", o.CaptureSlog,
+		"Whether to route the output of the log/slog default logger through this logger")
+
+	return fs
+}
+
+// Complete fills in the options from environment variables and from ConfigFile, if set.
+// Flags set explicitly in fs, which should be the flag set the options were added to,
+// take precedence over both, so that options are merged in the order
+// defaults < file < environment < flags. fs may be nil if the options weren't added to
+// a flag set.
+//
+// The environment variable for each option is named after its flag, upper-cased and
+// prefixed with EnvPrefix and an underscore, such as KUSION_LOG_OUTPUT_LEVEL for
+// --log_output_level. Environment variables are ignored if EnvPrefix is empty.
+//
+// Complete is typically called after parsing flags and before Configure. If it wasn't
+// called yet, Configure calls it, with the flag set the options were added to, if any.
+func (o *Options) Complete(fs *pflag.FlagSet) error {
+	// remember the flags set explicitly so they can be applied again on top of the file
+	ours := o.flagSet()
+	changed := make(map[string]string)
+	if fs != nil {
+		fs.Visit(func(f *pflag.Flag) {
+			if ours.Lookup(f.Name) != nil {
+				changed[f.Name] = f.Value.String()
+			}
+		})
+	}
+
+	// the environment may name the config file, so apply it before loading the file
+	if err := o.applyEnv(changed); err != nil {
+		return err
+	}
+
+	if o.ConfigFile != "" {
+		if err := o.LoadConfigFile(o.ConfigFile); err != nil {
+			return err
+		}
+
+		// the environment takes precedence over the file
+		if err := o.applyEnv(changed); err != nil {
+			return err
+		}
+	}
+
+	for name, value := range changed {
+		if err := fs.Set(name, value); err != nil {
+			return fmt.Errorf("failed to apply flag --%s: %v", name, err)
+		}
+	}
+
+	o.completed = true
+	return nil
+}
+
+// applyEnv sets the options from their environment variables, skipping the given flags.
+func (o *Options) applyEnv(skip map[string]string) error {
+	if o.EnvPrefix == "" {
+		return nil
+	}
+
+	var err error
+	o.flagSet().VisitAll(func(f *pflag.Flag) {
+		if _, ok := skip[f.Name]; ok || err != nil {
+			return
+		}
+
+		name := o.envName(f.Name)
+		if value, ok := os.LookupEnv(name); ok {
+			if setErr := f.Value.Set(value); setErr != nil {
+				err = fmt.Errorf("invalid value '%s' for environment variable %s: %v", value, name, setErr)
+			}
+		}
+	})
+
+	return err
+}
+
+// envName returns the name of the environment variable for the named flag.
+func (o *Options) envName(flag string) string {
+	return o.EnvPrefix + "_" + strings.ToUpper(flag)
+}
+
+// LoadConfigFile loads the options from a YAML or JSON file. Fields missing from the
+// file keep their current values, while unknown fields are rejected.
+func (o *Options) LoadConfigFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read log config file: %v", err)
+	}
+
+	if err := yaml.UnmarshalStrict(data, o); err != nil {
+		return fmt.Errorf("failed to parse log config file '%s': %v", path, err)
+	}
+
+	return nil
+}
+
+// Validate checks all options and returns every problem found, joined into a single error.
+// Configure calls it before applying the options, and it can also be used to reject invalid
+// options early, such as from a cobra PreRunE hook.
+func (o *Options) Validate() error {
+	var errs []error
+
+	if _, err := parseScopedLevels(o.OutputLevel, DefaultOutputLevel); err != nil {
+		errs = append(errs, fmt.Errorf("invalid output level: %v", err))
+	}
+	if o.StackTraceLevel != "" {
+		if _, err := parseScopedLevels(o.StackTraceLevel, DefaultStackTraceLevel); err != nil {
+			errs = append(errs, fmt.Errorf("invalid stack trace level: %v", err))
+		}
+	}
+
+	if o.OutputPath == "" && o.RotateOutputPath == "" && len(o.Sinks) == 0 {
+		errs = append(errs, errors.New("no log output configured, at least one of the output path, the rotating output path and sinks must be set"))
+	}
+	if o.RotateOutputPath != "" && filepath.Clean(o.RotateOutputPath) == filepath.Clean(o.OutputPath) {
+		errs = append(errs, fmt.Errorf("the rotating output path '%s' must differ from the output path", o.RotateOutputPath))
+	}
+
+	if err := checkWritable(o.OutputPath, false); err != nil {
+		errs = append(errs, fmt.Errorf("invalid output path: %v", err))
+	}
+	if err := checkWritable(o.ErrorOutputPath, false); err != nil {
+		errs = append(errs, fmt.Errorf("invalid error output path: %v", err))
+	}
+	if err := checkWritable(o.RotateOutputPath, true); err != nil {
+		errs = append(errs, fmt.Errorf("invalid rotating output path: %v", err))
+	}
+
+	for i := range o.Sinks {
+		for _, err := range o.Sinks[i].validate() {
+			errs = append(errs, fmt.Errorf("invalid sink %d: %v", i, err))
+		}
+	}
+
+	if !validEncoding(o.Format) && o.Format != "" {
+		errs = append(errs, fmt.Errorf("invalid format '%s', must be one of %s", o.Format, encodingListString))
+	}
+	if o.JSONEncoding && o.PrettyEncoding {
+		errs = append(errs, errors.New("the JSON and pretty encodings cannot be combined"))
+	} else if (o.JSONEncoding || o.PrettyEncoding) && o.Format != "" && o.Format != ConsoleEncoding && o.Format != o.encoding() {
+		errs = append(errs, fmt.Errorf("the %s encoding cannot be combined with the %s format", o.encoding(), o.Format))
+	}
+	switch o.Color {
+	case "", ColorAuto, ColorAlways, ColorNever:
+	default:
+		errs = append(errs, fmt.Errorf("invalid color '%s', must be one of %s", o.Color, colorListString))
+	}
+	switch o.PrettyTimestamps {
+	case "", TimestampsLocal, TimestampsRelative:
+	default:
+		errs = append(errs, fmt.Errorf("invalid pretty timestamps '%s', must be one of %s", o.PrettyTimestamps, timestampsListString))
+	}
+
+	if _, encoderErrs := o.encoderConfig(); len(encoderErrs) > 0 {
+		errs = append(errs, encoderErrs...)
+	}
+
+	if o.RotationMaxSizeBytes < 0 {
+		errs = append(errs, fmt.Errorf("invalid rotation max size %d, must not be negative", o.RotationMaxSizeBytes))
+	}
+	if o.RotationMaxAge < 0 {
+		errs = append(errs, fmt.Errorf("invalid rotation max age %d, must not be negative", o.RotationMaxAge))
+	}
+	if o.RotationMaxBackups < 0 {
+		errs = append(errs, fmt.Errorf("invalid rotation max backups %d, must not be negative", o.RotationMaxBackups))
+	}
+	if _, rotationErrs := o.rotation(); len(rotationErrs) > 0 {
+		errs = append(errs, rotationErrs...)
+	}
+
+	if _, err := o.sampling(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if o.Async {
+		if o.AsyncQueueSize <= 0 {
+			errs = append(errs, fmt.Errorf("invalid async queue size %d, must be positive", o.AsyncQueueSize))
+		}
+		if o.AsyncBufferSize < 0 {
+			errs = append(errs, fmt.Errorf("invalid async buffer size %d, must not be negative", o.AsyncBufferSize))
+		}
+		if d, err := time.ParseDuration(o.AsyncFlushInterval); err != nil {
+			errs = append(errs, fmt.Errorf("invalid async flush interval: %v", err))
+		} else if d <= 0 {
+			errs = append(errs, fmt.Errorf("invalid async flush interval '%s', must be positive", o.AsyncFlushInterval))
+		}
+		switch o.AsyncOverflow {
+		case OverflowBlock, OverflowDropOldest, OverflowDropNewest:
+		default:
+			errs = append(errs, fmt.Errorf("invalid async overflow policy '%s', must be one of %s", o.AsyncOverflow, overflowListString))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// checkWritable checks that logs can be written to the file system path, from the
+// permission bits of the file or of its parent directory, without writing to the file
+// system. The special values stdout and stderr, as well as URLs, aren't checked. If
+// createDirs is set, missing parent directories are allowed as long as the closest
+// existing one is writable.
+func checkWritable(path string, createDirs bool) error {
+	if path == "" || path == "stdout" || path == "stderr" || strings.Contains(path, "://") {
+		return nil
+	}
+
+	info, err := os.Stat(path)
+	if err == nil {
+		if info.IsDir() {
+			return fmt.Errorf("'%s' is a directory", path)
+		}
+		if info.Mode().Perm()&0o222 == 0 {
+			return fmt.Errorf("file '%s' is not writable", path)
+		}
+		return nil
+	}
+	if !os.IsNotExist(err) {
+		return err
+	}
+
+	dir := filepath.Dir(path)
+	for createDirs {
+		if _, err := os.Stat(dir); !os.IsNotExist(err) {
+			break
+		}
+		dir = filepath.Dir(dir)
+	}
+
+	info, err = os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("'%s' is not a directory", dir)
+	}
+	if info.Mode().Perm()&0o222 == 0 {
+		return fmt.Errorf("directory '%s' is not writable", dir)
+	}
+	return nil
+}
+
+// encoding returns the encoding of the log written to OutputPath and RotateOutputPath.
+// JSONEncoding and PrettyEncoding take precedence over the default format.
+func (o *Options) encoding() string {
+	switch {
+	case o.JSONEncoding:
+		return JSONEncoding
+	case o.PrettyEncoding:
+		return PrettyEncoding
+	case o.Format != "":
+		return o.Format
+	}
+	return ConsoleEncoding
+}
+
+// sampling parses the per-scope sampling.
+func (o *Options) sampling() (*scopedSampling, error) {
+	var interval time.Duration
+	if o.Sampling != "" {
+		var err error
+		if interval, err = time.ParseDuration(o.SamplingInterval); err != nil {
+			return nil, fmt.Errorf("invalid sampling interval: %v", err)
+		}
+		if interval <= 0 {
+			return nil, fmt.Errorf("invalid sampling interval '%s', must be positive", o.SamplingInterval)
+		}
+	}
+
+	ss, err := parseScopedSampling(o.Sampling, interval)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sampling: %v", err)
+	}
+	return ss, nil
+}
+
+// scopedLevels is the parsed form of a per-scope level list such as "info,engine:debug".
+type scopedLevels struct {
+	// all is the level applied to scopes not listed in scopes.
+	all Level
+	// scopes maps scope names to their explicitly requested level.
+	scopes map[string]Level
+}
+
+// parseScopedLevels parses a comma-separated list of levels in the form <scope>:<level>. Entries without
+// a scope override fallback for all scopes not listed explicitly. Listed scopes don't need to be registered
+// yet, as scopes such as the ones of logr loggers are registered lazily.
+func parseScopedLevels(levels string, fallback Level) (*scopedLevels, error) {
+	if strings.TrimSpace(levels) == "" {
+		return nil, fmt.Errorf("empty level, must be one of %s", levelListString)
+	}
+
+	sl := &scopedLevels{
+		all:    fallback,
+		scopes: make(map[string]Level),
+	}
+
+	for _, entry := range strings.Split(levels, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		scope, levelName, scoped := strings.Cut(entry, ":")
+		if !scoped {
+			scope, levelName = "", entry
+		}
+
+		level, ok := stringToLevel[levelName]
+		if !ok {
+			if scoped {
+				return nil, fmt.Errorf("invalid level '%s' for scope '%s', must be one of %s", levelName, scope, levelListString)
+			}
+			return nil, fmt.Errorf("invalid level '%s', must be one of %s", levelName, levelListString)
+		}
+
+		if !scoped {
+			sl.all = level
+			continue
+		}
+
+		if err := checkScopeName(scope); err != nil {
+			return nil, err
+		}
+		sl.scopes[scope] = level
+	}
+
+	return sl, nil
+}
+
+// checkScopeName checks that a scope listed in the options is a well-formed scope name.
+// The scope doesn't need to be registered yet, as scopes such as the ones of logr loggers
+// are registered lazily.
+func checkScopeName(scope string) error {
+	if scope == "" || strings.ContainsAny(scope, ". ") {
+		return fmt.Errorf("invalid scope '%s', scope names cannot be empty or contain periods or spaces", scope)
+	}
+	return nil
+}
+
+// levelFor returns the level requested for the named scope.
+func (sl *scopedLevels) levelFor(scope string) Level {
+	if l, ok := sl.scopes[scope]; ok {
+		return l
+	}
+	return sl.all
+}
+
+// scopeNames returns the sorted names of all registered scopes.
+func scopeNames() []string {
+	all := Scopes()
+	names := make([]string, 0, len(all))
+	for name := range all {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
 }
@@ -15,6 +15,8 @@
 package log
 
 import (
+	"os"
+	"path/filepath"
 	"reflect"
 	"strconv"
 	"strings"
@@ -23,143 +25,547 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// publicOptions returns the options without the state recorded by AddFlags and Complete.
+func publicOptions(o *Options) Options {
+	p := *o
+	p.flags, p.completed = nil, false
+	return p
+}
+
 func TestOptions(t *testing.T) {
 	cases := []struct {
 		cmdLine string
 		result  Options
 	}{
 		{"--log_as_json", Options{
-			OutputPath:         DefaultOutputPath,
-			ErrorOutputPath:    DefaultErrorOutputPath,
-			JSONEncoding:       true,
-			RotationMaxAge:     DefaultRotationMaxAge,
-			RotationMaxSize:    DefaultRotationMaxSize,
-			RotationMaxBackups: DefaultRotationMaxBackups,
-			OutputLevel:        "info",
-			StackTraceLevel:    "none",
-			LogCaller:          false,
+			EnvPrefix:            DefaultEnvPrefix,
+			OutputPath:           DefaultOutputPath,
+			ErrorOutputPath:      DefaultErrorOutputPath,
+			JSONEncoding:         true,
+			RotationMaxAge:       DefaultRotationMaxAge,
+			RotationMaxSizeBytes: DefaultRotationMaxSize,
+			RotationMaxBackups:   DefaultRotationMaxBackups,
+			RotationBackupFormat: DefaultRotationBackupFormat,
+			AsyncQueueSize:       DefaultAsyncQueueSize,
+			AsyncBufferSize:      DefaultAsyncBufferSize,
+			AsyncFlushInterval:   DefaultAsyncFlushInterval,
+			AsyncOverflow:        DefaultAsyncOverflow,
+			SamplingInterval:     DefaultSamplingInterval,
+			Format:               DefaultFormat,
+			Color:                DefaultColor,
+			PrettyTimestamps:     DefaultPrettyTimestamps,
+			TimeFormat:           DefaultTimeFormat,
+			TimeZone:             DefaultTimeZone,
+			LevelCase:            DefaultLevelCase,
+			CallerFormat:         DefaultCallerFormat,
+			OutputLevel:          "info",
+			StackTraceLevel:      "none",
+			LogCaller:            false,
 		}},
 
 		{"--log_path stdout", Options{
-			OutputPath:         "stdout",
-			ErrorOutputPath:    DefaultErrorOutputPath,
-			RotationMaxAge:     DefaultRotationMaxAge,
-			RotationMaxSize:    DefaultRotationMaxSize,
-			RotationMaxBackups: DefaultRotationMaxBackups,
-			OutputLevel:        "info",
-			StackTraceLevel:    "none",
-			LogCaller:          false,
+			EnvPrefix:            DefaultEnvPrefix,
+			OutputPath:           "stdout",
+			ErrorOutputPath:      DefaultErrorOutputPath,
+			RotationMaxAge:       DefaultRotationMaxAge,
+			RotationMaxSizeBytes: DefaultRotationMaxSize,
+			RotationMaxBackups:   DefaultRotationMaxBackups,
+			RotationBackupFormat: DefaultRotationBackupFormat,
+			AsyncQueueSize:       DefaultAsyncQueueSize,
+			AsyncBufferSize:      DefaultAsyncBufferSize,
+			AsyncFlushInterval:   DefaultAsyncFlushInterval,
+			AsyncOverflow:        DefaultAsyncOverflow,
+			SamplingInterval:     DefaultSamplingInterval,
+			Format:               DefaultFormat,
+			Color:                DefaultColor,
+			PrettyTimestamps:     DefaultPrettyTimestamps,
+			TimeFormat:           DefaultTimeFormat,
+			TimeZone:             DefaultTimeZone,
+			LevelCase:            DefaultLevelCase,
+			CallerFormat:         DefaultCallerFormat,
+			OutputLevel:          "info",
+			StackTraceLevel:      "none",
+			LogCaller:            false,
+		}},
+
+		{"--log_error_path stdout", Options{
+			EnvPrefix:            DefaultEnvPrefix,
+			OutputPath:           DefaultOutputPath,
+			ErrorOutputPath:      "stdout",
+			RotationMaxAge:       DefaultRotationMaxAge,
+			RotationMaxSizeBytes: DefaultRotationMaxSize,
+			RotationMaxBackups:   DefaultRotationMaxBackups,
+			RotationBackupFormat: DefaultRotationBackupFormat,
+			AsyncQueueSize:       DefaultAsyncQueueSize,
+			AsyncBufferSize:      DefaultAsyncBufferSize,
+			AsyncFlushInterval:   DefaultAsyncFlushInterval,
+			AsyncOverflow:        DefaultAsyncOverflow,
+			SamplingInterval:     DefaultSamplingInterval,
+			Format:               DefaultFormat,
+			Color:                DefaultColor,
+			PrettyTimestamps:     DefaultPrettyTimestamps,
+			TimeFormat:           DefaultTimeFormat,
+			TimeZone:             DefaultTimeZone,
+			LevelCase:            DefaultLevelCase,
+			CallerFormat:         DefaultCallerFormat,
+			OutputLevel:          "info",
+			StackTraceLevel:      "none",
+			LogCaller:            false,
+		}},
+
+		{"--log_pretty --log_color always --log_pretty_timestamps relative", Options{
+			EnvPrefix:            DefaultEnvPrefix,
+			OutputPath:           DefaultOutputPath,
+			ErrorOutputPath:      DefaultErrorOutputPath,
+			PrettyEncoding:       true,
+			RotationMaxAge:       DefaultRotationMaxAge,
+			RotationMaxSizeBytes: DefaultRotationMaxSize,
+			RotationMaxBackups:   DefaultRotationMaxBackups,
+			RotationBackupFormat: DefaultRotationBackupFormat,
+			AsyncQueueSize:       DefaultAsyncQueueSize,
+			AsyncBufferSize:      DefaultAsyncBufferSize,
+			AsyncFlushInterval:   DefaultAsyncFlushInterval,
+			AsyncOverflow:        DefaultAsyncOverflow,
+			SamplingInterval:     DefaultSamplingInterval,
+			Format:               DefaultFormat,
+			Color:                ColorAlways,
+			PrettyTimestamps:     TimestampsRelative,
+			TimeFormat:           DefaultTimeFormat,
+			TimeZone:             DefaultTimeZone,
+			LevelCase:            DefaultLevelCase,
+			CallerFormat:         DefaultCallerFormat,
+			OutputLevel:          "info",
+			StackTraceLevel:      "none",
+			LogCaller:            false,
+		}},
+
+		{"--log_time_format rfc3339 --log_timezone Local --log_keys time:ts --log_level_case upper --log_caller_format full", Options{
+			EnvPrefix:            DefaultEnvPrefix,
+			OutputPath:           DefaultOutputPath,
+			ErrorOutputPath:      DefaultErrorOutputPath,
+			RotationMaxAge:       DefaultRotationMaxAge,
+			RotationMaxSizeBytes: DefaultRotationMaxSize,
+			RotationMaxBackups:   DefaultRotationMaxBackups,
+			RotationBackupFormat: DefaultRotationBackupFormat,
+			AsyncQueueSize:       DefaultAsyncQueueSize,
+			AsyncBufferSize:      DefaultAsyncBufferSize,
+			AsyncFlushInterval:   DefaultAsyncFlushInterval,
+			AsyncOverflow:        DefaultAsyncOverflow,
+			SamplingInterval:     DefaultSamplingInterval,
+			Format:               DefaultFormat,
+			Color:                DefaultColor,
+			PrettyTimestamps:     DefaultPrettyTimestamps,
+			TimeFormat:           TimeFormatRFC3339,
+			TimeZone:             "Local",
+			EncoderKeys:          "time:ts",
+			LevelCase:            LevelCaseUpper,
+			CallerFormat:         CallerFull,
+			OutputLevel:          "info",
+			StackTraceLevel:      "none",
+			LogCaller:            false,
+		}},
+
+		{"--log_format ecs", Options{
+			EnvPrefix:            DefaultEnvPrefix,
+			OutputPath:           DefaultOutputPath,
+			ErrorOutputPath:      DefaultErrorOutputPath,
+			RotationMaxAge:       DefaultRotationMaxAge,
+			RotationMaxSizeBytes: DefaultRotationMaxSize,
+			RotationMaxBackups:   DefaultRotationMaxBackups,
+			RotationBackupFormat: DefaultRotationBackupFormat,
+			AsyncQueueSize:       DefaultAsyncQueueSize,
+			AsyncBufferSize:      DefaultAsyncBufferSize,
+			AsyncFlushInterval:   DefaultAsyncFlushInterval,
+			AsyncOverflow:        DefaultAsyncOverflow,
+			SamplingInterval:     DefaultSamplingInterval,
+			Format:               ECSEncoding,
+			Color:                DefaultColor,
+			PrettyTimestamps:     DefaultPrettyTimestamps,
+			TimeFormat:           DefaultTimeFormat,
+			TimeZone:             DefaultTimeZone,
+			LevelCase:            DefaultLevelCase,
+			CallerFormat:         DefaultCallerFormat,
+			OutputLevel:          "info",
+			StackTraceLevel:      "none",
+			LogCaller:            false,
 		}},
 
 		{"--log_caller", Options{
-			OutputPath:         DefaultOutputPath,
-			ErrorOutputPath:    DefaultErrorOutputPath,
-			RotationMaxAge:     DefaultRotationMaxAge,
-			RotationMaxSize:    DefaultRotationMaxSize,
-			RotationMaxBackups: DefaultRotationMaxBackups,
-			OutputLevel:        "info",
-			StackTraceLevel:    "none",
-			LogCaller:          true,
+			EnvPrefix:            DefaultEnvPrefix,
+			OutputPath:           DefaultOutputPath,
+			ErrorOutputPath:      DefaultErrorOutputPath,
+			RotationMaxAge:       DefaultRotationMaxAge,
+			RotationMaxSizeBytes: DefaultRotationMaxSize,
+			RotationMaxBackups:   DefaultRotationMaxBackups,
+			RotationBackupFormat: DefaultRotationBackupFormat,
+			AsyncQueueSize:       DefaultAsyncQueueSize,
+			AsyncBufferSize:      DefaultAsyncBufferSize,
+			AsyncFlushInterval:   DefaultAsyncFlushInterval,
+			AsyncOverflow:        DefaultAsyncOverflow,
+			SamplingInterval:     DefaultSamplingInterval,
+			Format:               DefaultFormat,
+			Color:                DefaultColor,
+			PrettyTimestamps:     DefaultPrettyTimestamps,
+			TimeFormat:           DefaultTimeFormat,
+			TimeZone:             DefaultTimeZone,
+			LevelCase:            DefaultLevelCase,
+			CallerFormat:         DefaultCallerFormat,
+			OutputLevel:          "info",
+			StackTraceLevel:      "none",
+			LogCaller:            true,
 		}},
 
 		{"--log_stacktrace_level debug", Options{
-			OutputPath:         DefaultOutputPath,
-			ErrorOutputPath:    DefaultErrorOutputPath,
-			RotationMaxAge:     DefaultRotationMaxAge,
-			RotationMaxSize:    DefaultRotationMaxSize,
-			RotationMaxBackups: DefaultRotationMaxBackups,
-			OutputLevel:        "info",
-			StackTraceLevel:    "debug",
-			LogCaller:          false,
+			EnvPrefix:            DefaultEnvPrefix,
+			OutputPath:           DefaultOutputPath,
+			ErrorOutputPath:      DefaultErrorOutputPath,
+			RotationMaxAge:       DefaultRotationMaxAge,
+			RotationMaxSizeBytes: DefaultRotationMaxSize,
+			RotationMaxBackups:   DefaultRotationMaxBackups,
+			RotationBackupFormat: DefaultRotationBackupFormat,
+			AsyncQueueSize:       DefaultAsyncQueueSize,
+			AsyncBufferSize:      DefaultAsyncBufferSize,
+			AsyncFlushInterval:   DefaultAsyncFlushInterval,
+			AsyncOverflow:        DefaultAsyncOverflow,
+			SamplingInterval:     DefaultSamplingInterval,
+			Format:               DefaultFormat,
+			Color:                DefaultColor,
+			PrettyTimestamps:     DefaultPrettyTimestamps,
+			TimeFormat:           DefaultTimeFormat,
+			TimeZone:             DefaultTimeZone,
+			LevelCase:            DefaultLevelCase,
+			CallerFormat:         DefaultCallerFormat,
+			OutputLevel:          "info",
+			StackTraceLevel:      "debug",
+			LogCaller:            false,
 		}},
 
 		{"--log_stacktrace_level info", Options{
-			OutputPath:         DefaultOutputPath,
-			ErrorOutputPath:    DefaultErrorOutputPath,
-			RotationMaxAge:     DefaultRotationMaxAge,
-			RotationMaxSize:    DefaultRotationMaxSize,
-			RotationMaxBackups: DefaultRotationMaxBackups,
-			OutputLevel:        "info",
-			StackTraceLevel:    "info",
-			LogCaller:          false,
+			EnvPrefix:            DefaultEnvPrefix,
+			OutputPath:           DefaultOutputPath,
+			ErrorOutputPath:      DefaultErrorOutputPath,
+			RotationMaxAge:       DefaultRotationMaxAge,
+			RotationMaxSizeBytes: DefaultRotationMaxSize,
+			RotationMaxBackups:   DefaultRotationMaxBackups,
+			RotationBackupFormat: DefaultRotationBackupFormat,
+			AsyncQueueSize:       DefaultAsyncQueueSize,
+			AsyncBufferSize:      DefaultAsyncBufferSize,
+			AsyncFlushInterval:   DefaultAsyncFlushInterval,
+			AsyncOverflow:        DefaultAsyncOverflow,
+			SamplingInterval:     DefaultSamplingInterval,
+			Format:               DefaultFormat,
+			Color:                DefaultColor,
+			PrettyTimestamps:     DefaultPrettyTimestamps,
+			TimeFormat:           DefaultTimeFormat,
+			TimeZone:             DefaultTimeZone,
+			LevelCase:            DefaultLevelCase,
+			CallerFormat:         DefaultCallerFormat,
+			OutputLevel:          "info",
+			StackTraceLevel:      "info",
+			LogCaller:            false,
 		}},
 
 		{"--log_stacktrace_level warn", Options{
-			OutputPath:         DefaultOutputPath,
-			ErrorOutputPath:    DefaultErrorOutputPath,
-			RotationMaxAge:     DefaultRotationMaxAge,
-			RotationMaxSize:    DefaultRotationMaxSize,
-			RotationMaxBackups: DefaultRotationMaxBackups,
-			OutputLevel:        "info",
-			StackTraceLevel:    "warn",
-			LogCaller:          false,
+			EnvPrefix:            DefaultEnvPrefix,
+			OutputPath:           DefaultOutputPath,
+			ErrorOutputPath:      DefaultErrorOutputPath,
+			RotationMaxAge:       DefaultRotationMaxAge,
+			RotationMaxSizeBytes: DefaultRotationMaxSize,
+			RotationMaxBackups:   DefaultRotationMaxBackups,
+			RotationBackupFormat: DefaultRotationBackupFormat,
+			AsyncQueueSize:       DefaultAsyncQueueSize,
+			AsyncBufferSize:      DefaultAsyncBufferSize,
+			AsyncFlushInterval:   DefaultAsyncFlushInterval,
+			AsyncOverflow:        DefaultAsyncOverflow,
+			SamplingInterval:     DefaultSamplingInterval,
+			Format:               DefaultFormat,
+			Color:                DefaultColor,
+			PrettyTimestamps:     DefaultPrettyTimestamps,
+			TimeFormat:           DefaultTimeFormat,
+			TimeZone:             DefaultTimeZone,
+			LevelCase:            DefaultLevelCase,
+			CallerFormat:         DefaultCallerFormat,
+			OutputLevel:          "info",
+			StackTraceLevel:      "warn",
+			LogCaller:            false,
 		}},
 
 		{"--log_output_level debug", Options{
-			OutputPath:         DefaultOutputPath,
-			ErrorOutputPath:    DefaultErrorOutputPath,
-			RotationMaxAge:     DefaultRotationMaxAge,
-			RotationMaxSize:    DefaultRotationMaxSize,
-			RotationMaxBackups: DefaultRotationMaxBackups,
-			OutputLevel:        "debug",
-			StackTraceLevel:    "none",
-			LogCaller:          false,
+			EnvPrefix:            DefaultEnvPrefix,
+			OutputPath:           DefaultOutputPath,
+			ErrorOutputPath:      DefaultErrorOutputPath,
+			RotationMaxAge:       DefaultRotationMaxAge,
+			RotationMaxSizeBytes: DefaultRotationMaxSize,
+			RotationMaxBackups:   DefaultRotationMaxBackups,
+			RotationBackupFormat: DefaultRotationBackupFormat,
+			AsyncQueueSize:       DefaultAsyncQueueSize,
+			AsyncBufferSize:      DefaultAsyncBufferSize,
+			AsyncFlushInterval:   DefaultAsyncFlushInterval,
+			AsyncOverflow:        DefaultAsyncOverflow,
+			SamplingInterval:     DefaultSamplingInterval,
+			Format:               DefaultFormat,
+			Color:                DefaultColor,
+			PrettyTimestamps:     DefaultPrettyTimestamps,
+			TimeFormat:           DefaultTimeFormat,
+			TimeZone:             DefaultTimeZone,
+			LevelCase:            DefaultLevelCase,
+			CallerFormat:         DefaultCallerFormat,
+			OutputLevel:          "debug",
+			StackTraceLevel:      "none",
+			LogCaller:            false,
 		}},
 
 		{"--log_output_level warn", Options{
-			OutputPath:         DefaultOutputPath,
-			ErrorOutputPath:    DefaultErrorOutputPath,
-			RotationMaxAge:     DefaultRotationMaxAge,
-			RotationMaxSize:    DefaultRotationMaxSize,
-			RotationMaxBackups: DefaultRotationMaxBackups,
-			OutputLevel:        "warn",
-			StackTraceLevel:    "none",
-			LogCaller:          false,
+			EnvPrefix:            DefaultEnvPrefix,
+			OutputPath:           DefaultOutputPath,
+			ErrorOutputPath:      DefaultErrorOutputPath,
+			RotationMaxAge:       DefaultRotationMaxAge,
+			RotationMaxSizeBytes: DefaultRotationMaxSize,
+			RotationMaxBackups:   DefaultRotationMaxBackups,
+			RotationBackupFormat: DefaultRotationBackupFormat,
+			AsyncQueueSize:       DefaultAsyncQueueSize,
+			AsyncBufferSize:      DefaultAsyncBufferSize,
+			AsyncFlushInterval:   DefaultAsyncFlushInterval,
+			AsyncOverflow:        DefaultAsyncOverflow,
+			SamplingInterval:     DefaultSamplingInterval,
+			Format:               DefaultFormat,
+			Color:                DefaultColor,
+			PrettyTimestamps:     DefaultPrettyTimestamps,
+			TimeFormat:           DefaultTimeFormat,
+			TimeZone:             DefaultTimeZone,
+			LevelCase:            DefaultLevelCase,
+			CallerFormat:         DefaultCallerFormat,
+			OutputLevel:          "warn",
+			StackTraceLevel:      "none",
+			LogCaller:            false,
+		}},
+
+		{"--log_output_level info,default:debug", Options{
+			EnvPrefix:            DefaultEnvPrefix,
+			OutputPath:           DefaultOutputPath,
+			ErrorOutputPath:      DefaultErrorOutputPath,
+			RotationMaxAge:       DefaultRotationMaxAge,
+			RotationMaxSizeBytes: DefaultRotationMaxSize,
+			RotationMaxBackups:   DefaultRotationMaxBackups,
+			RotationBackupFormat: DefaultRotationBackupFormat,
+			AsyncQueueSize:       DefaultAsyncQueueSize,
+			AsyncBufferSize:      DefaultAsyncBufferSize,
+			AsyncFlushInterval:   DefaultAsyncFlushInterval,
+			AsyncOverflow:        DefaultAsyncOverflow,
+			SamplingInterval:     DefaultSamplingInterval,
+			Format:               DefaultFormat,
+			Color:                DefaultColor,
+			PrettyTimestamps:     DefaultPrettyTimestamps,
+			TimeFormat:           DefaultTimeFormat,
+			TimeZone:             DefaultTimeZone,
+			LevelCase:            DefaultLevelCase,
+			CallerFormat:         DefaultCallerFormat,
+			OutputLevel:          "info,default:debug",
+			StackTraceLevel:      "none",
+			LogCaller:            false,
+		}},
+
+		{"--log_stacktrace_level default:error", Options{
+			EnvPrefix:            DefaultEnvPrefix,
+			OutputPath:           DefaultOutputPath,
+			ErrorOutputPath:      DefaultErrorOutputPath,
+			RotationMaxAge:       DefaultRotationMaxAge,
+			RotationMaxSizeBytes: DefaultRotationMaxSize,
+			RotationMaxBackups:   DefaultRotationMaxBackups,
+			RotationBackupFormat: DefaultRotationBackupFormat,
+			AsyncQueueSize:       DefaultAsyncQueueSize,
+			AsyncBufferSize:      DefaultAsyncBufferSize,
+			AsyncFlushInterval:   DefaultAsyncFlushInterval,
+			AsyncOverflow:        DefaultAsyncOverflow,
+			SamplingInterval:     DefaultSamplingInterval,
+			Format:               DefaultFormat,
+			Color:                DefaultColor,
+			PrettyTimestamps:     DefaultPrettyTimestamps,
+			TimeFormat:           DefaultTimeFormat,
+			TimeZone:             DefaultTimeZone,
+			LevelCase:            DefaultLevelCase,
+			CallerFormat:         DefaultCallerFormat,
+			OutputLevel:          "info",
+			StackTraceLevel:      "default:error",
+			LogCaller:            false,
 		}},
 
 		{"--log_rotate_path foobar", Options{
-			OutputPath:         DefaultOutputPath,
-			ErrorOutputPath:    DefaultErrorOutputPath,
-			RotateOutputPath:   "foobar",
-			RotationMaxAge:     DefaultRotationMaxAge,
-			RotationMaxSize:    DefaultRotationMaxSize,
-			RotationMaxBackups: DefaultRotationMaxBackups,
-			OutputLevel:        "info",
-			StackTraceLevel:    "none",
-			LogCaller:          false,
+			EnvPrefix:            DefaultEnvPrefix,
+			OutputPath:           DefaultOutputPath,
+			ErrorOutputPath:      DefaultErrorOutputPath,
+			RotateOutputPath:     "foobar",
+			RotationMaxAge:       DefaultRotationMaxAge,
+			RotationMaxSizeBytes: DefaultRotationMaxSize,
+			RotationMaxBackups:   DefaultRotationMaxBackups,
+			RotationBackupFormat: DefaultRotationBackupFormat,
+			AsyncQueueSize:       DefaultAsyncQueueSize,
+			AsyncBufferSize:      DefaultAsyncBufferSize,
+			AsyncFlushInterval:   DefaultAsyncFlushInterval,
+			AsyncOverflow:        DefaultAsyncOverflow,
+			SamplingInterval:     DefaultSamplingInterval,
+			Format:               DefaultFormat,
+			Color:                DefaultColor,
+			PrettyTimestamps:     DefaultPrettyTimestamps,
+			TimeFormat:           DefaultTimeFormat,
+			TimeZone:             DefaultTimeZone,
+			LevelCase:            DefaultLevelCase,
+			CallerFormat:         DefaultCallerFormat,
+			OutputLevel:          "info",
+			StackTraceLevel:      "none",
+			LogCaller:            false,
 		}},
 
 		{"--log_rotate_max_age 1234", Options{
-			OutputPath:         DefaultOutputPath,
-			ErrorOutputPath:    DefaultErrorOutputPath,
-			RotationMaxAge:     1234,
-			RotationMaxSize:    DefaultRotationMaxSize,
-			RotationMaxBackups: DefaultRotationMaxBackups,
-			OutputLevel:        "info",
-			StackTraceLevel:    "none",
-			LogCaller:          false,
-		}},
-
-		{"--log_rotate_max_size 1234", Options{
-			OutputPath:         DefaultOutputPath,
-			ErrorOutputPath:    DefaultErrorOutputPath,
-			RotationMaxAge:     DefaultRotationMaxAge,
-			RotationMaxSize:    1234,
-			RotationMaxBackups: DefaultRotationMaxBackups,
-			OutputLevel:        "info",
-			StackTraceLevel:    "none",
-			LogCaller:          false,
+			EnvPrefix:            DefaultEnvPrefix,
+			OutputPath:           DefaultOutputPath,
+			ErrorOutputPath:      DefaultErrorOutputPath,
+			RotationMaxAge:       1234,
+			RotationMaxSizeBytes: DefaultRotationMaxSize,
+			RotationMaxBackups:   DefaultRotationMaxBackups,
+			RotationBackupFormat: DefaultRotationBackupFormat,
+			AsyncQueueSize:       DefaultAsyncQueueSize,
+			AsyncBufferSize:      DefaultAsyncBufferSize,
+			AsyncFlushInterval:   DefaultAsyncFlushInterval,
+			AsyncOverflow:        DefaultAsyncOverflow,
+			SamplingInterval:     DefaultSamplingInterval,
+			Format:               DefaultFormat,
+			Color:                DefaultColor,
+			PrettyTimestamps:     DefaultPrettyTimestamps,
+			TimeFormat:           DefaultTimeFormat,
+			TimeZone:             DefaultTimeZone,
+			LevelCase:            DefaultLevelCase,
+			CallerFormat:         DefaultCallerFormat,
+			OutputLevel:          "info",
+			StackTraceLevel:      "none",
+			LogCaller:            false,
+		}},
+
+		{"--log_rotate_max_size 1234Mi", Options{
+			EnvPrefix:            DefaultEnvPrefix,
+			OutputPath:           DefaultOutputPath,
+			ErrorOutputPath:      DefaultErrorOutputPath,
+			RotationMaxAge:       DefaultRotationMaxAge,
+			RotationMaxSizeBytes: 1234 * Mebibyte,
+			RotationMaxBackups:   DefaultRotationMaxBackups,
+			RotationBackupFormat: DefaultRotationBackupFormat,
+			AsyncQueueSize:       DefaultAsyncQueueSize,
+			AsyncBufferSize:      DefaultAsyncBufferSize,
+			AsyncFlushInterval:   DefaultAsyncFlushInterval,
+			AsyncOverflow:        DefaultAsyncOverflow,
+			SamplingInterval:     DefaultSamplingInterval,
+			Format:               DefaultFormat,
+			Color:                DefaultColor,
+			PrettyTimestamps:     DefaultPrettyTimestamps,
+			TimeFormat:           DefaultTimeFormat,
+			TimeZone:             DefaultTimeZone,
+			LevelCase:            DefaultLevelCase,
+			CallerFormat:         DefaultCallerFormat,
+			OutputLevel:          "info",
+			StackTraceLevel:      "none",
+			LogCaller:            false,
+		}},
+
+		{"--log_rotate_max_size 1G", Options{
+			EnvPrefix:            DefaultEnvPrefix,
+			OutputPath:           DefaultOutputPath,
+			ErrorOutputPath:      DefaultErrorOutputPath,
+			RotationMaxAge:       DefaultRotationMaxAge,
+			RotationMaxSizeBytes: 1000 * 1000 * 1000,
+			RotationMaxBackups:   DefaultRotationMaxBackups,
+			RotationBackupFormat: DefaultRotationBackupFormat,
+			AsyncQueueSize:       DefaultAsyncQueueSize,
+			AsyncBufferSize:      DefaultAsyncBufferSize,
+			AsyncFlushInterval:   DefaultAsyncFlushInterval,
+			AsyncOverflow:        DefaultAsyncOverflow,
+			SamplingInterval:     DefaultSamplingInterval,
+			Format:               DefaultFormat,
+			Color:                DefaultColor,
+			PrettyTimestamps:     DefaultPrettyTimestamps,
+			TimeFormat:           DefaultTimeFormat,
+			TimeZone:             DefaultTimeZone,
+			LevelCase:            DefaultLevelCase,
+			CallerFormat:         DefaultCallerFormat,
+			OutputLevel:          "info",
+			StackTraceLevel:      "none",
+			LogCaller:            false,
 		}},
 
 		{"--log_rotate_max_backups 1234", Options{
-			OutputPath:         DefaultOutputPath,
-			ErrorOutputPath:    DefaultErrorOutputPath,
-			RotationMaxAge:     DefaultRotationMaxAge,
-			RotationMaxSize:    DefaultRotationMaxSize,
-			RotationMaxBackups: 1234,
-			OutputLevel:        "info",
-			StackTraceLevel:    "none",
-			LogCaller:          false,
+			EnvPrefix:            DefaultEnvPrefix,
+			OutputPath:           DefaultOutputPath,
+			ErrorOutputPath:      DefaultErrorOutputPath,
+			RotationMaxAge:       DefaultRotationMaxAge,
+			RotationMaxSizeBytes: DefaultRotationMaxSize,
+			RotationMaxBackups:   1234,
+			RotationBackupFormat: DefaultRotationBackupFormat,
+			AsyncQueueSize:       DefaultAsyncQueueSize,
+			AsyncBufferSize:      DefaultAsyncBufferSize,
+			AsyncFlushInterval:   DefaultAsyncFlushInterval,
+			AsyncOverflow:        DefaultAsyncOverflow,
+			SamplingInterval:     DefaultSamplingInterval,
+			Format:               DefaultFormat,
+			Color:                DefaultColor,
+			PrettyTimestamps:     DefaultPrettyTimestamps,
+			TimeFormat:           DefaultTimeFormat,
+			TimeZone:             DefaultTimeZone,
+			LevelCase:            DefaultLevelCase,
+			CallerFormat:         DefaultCallerFormat,
+			OutputLevel:          "info",
+			StackTraceLevel:      "none",
+			LogCaller:            false,
+		}},
+
+		{"--log_rotate_compress", Options{
+			EnvPrefix:            DefaultEnvPrefix,
+			OutputPath:           DefaultOutputPath,
+			ErrorOutputPath:      DefaultErrorOutputPath,
+			RotationMaxAge:       DefaultRotationMaxAge,
+			RotationMaxSizeBytes: DefaultRotationMaxSize,
+			RotationMaxBackups:   DefaultRotationMaxBackups,
+			RotationBackupFormat: DefaultRotationBackupFormat,
+			AsyncQueueSize:       DefaultAsyncQueueSize,
+			AsyncBufferSize:      DefaultAsyncBufferSize,
+			AsyncFlushInterval:   DefaultAsyncFlushInterval,
+			AsyncOverflow:        DefaultAsyncOverflow,
+			SamplingInterval:     DefaultSamplingInterval,
+			Format:               DefaultFormat,
+			Color:                DefaultColor,
+			PrettyTimestamps:     DefaultPrettyTimestamps,
+			TimeFormat:           DefaultTimeFormat,
+			TimeZone:             DefaultTimeZone,
+			LevelCase:            DefaultLevelCase,
+			CallerFormat:         DefaultCallerFormat,
+			RotationCompress:     CompressGzip,
+			OutputLevel:          "info",
+			StackTraceLevel:      "none",
+			LogCaller:            false,
+		}},
+
+		{"--log_rotate_compress=zstd", Options{
+			EnvPrefix:            DefaultEnvPrefix,
+			OutputPath:           DefaultOutputPath,
+			ErrorOutputPath:      DefaultErrorOutputPath,
+			RotationMaxAge:       DefaultRotationMaxAge,
+			RotationMaxSizeBytes: DefaultRotationMaxSize,
+			RotationMaxBackups:   DefaultRotationMaxBackups,
+			RotationBackupFormat: DefaultRotationBackupFormat,
+			AsyncQueueSize:       DefaultAsyncQueueSize,
+			AsyncBufferSize:      DefaultAsyncBufferSize,
+			AsyncFlushInterval:   DefaultAsyncFlushInterval,
+			AsyncOverflow:        DefaultAsyncOverflow,
+			SamplingInterval:     DefaultSamplingInterval,
+			Format:               DefaultFormat,
+			Color:                DefaultColor,
+			PrettyTimestamps:     DefaultPrettyTimestamps,
+			TimeFormat:           DefaultTimeFormat,
+			TimeZone:             DefaultTimeZone,
+			LevelCase:            DefaultLevelCase,
+			CallerFormat:         DefaultCallerFormat,
+			RotationCompress:     CompressZstd,
+			OutputLevel:          "info",
+			StackTraceLevel:      "none",
+			LogCaller:            false,
 		}},
 	}
 
@@ -175,10 +581,558 @@ func TestOptions(t *testing.T) {
 					t.Errorf("Got %v, expecting success", err)
 				}
 
-				if !reflect.DeepEqual(c.result, *o) {
+				if !reflect.DeepEqual(c.result, publicOptions(o)) {
 					t.Errorf("Got %v, expected %v", *o, c.result)
 				}
 			})
 		}
 	}
 }
+
+func TestOptionsConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	yamlFile := filepath.Join(dir, "log.yaml")
+	jsonFile := filepath.Join(dir, "log.json")
+	badFile := filepath.Join(dir, "bad.yaml")
+	_ = os.WriteFile(yamlFile, []byte("outputPath: stderr\nrotationMaxAge: 7\nrotationMaxSize: 1Gi\noutputLevel: debug\nlogCaller: true\n"), 0o644)
+	_ = os.WriteFile(jsonFile, []byte(`{"jsonEncoding": true, "rotateOutputPath": "/tmp/log", "rotationMaxSize": "5Mi"}`), 0o644)
+	_ = os.WriteFile(badFile, []byte("outputLevl: debug\n"), 0o644)
+
+	cases := []struct {
+		cmdLine string
+		result  func(o *Options)
+		err     string
+	}{
+		{
+			cmdLine: "--log_config " + yamlFile,
+			result: func(o *Options) {
+				o.ConfigFile = yamlFile
+				o.OutputPath = "stderr"
+				o.RotationMaxAge = 7
+				o.RotationMaxSizeBytes = Gibibyte
+				o.OutputLevel = "debug"
+				o.LogCaller = true
+			},
+		},
+		{
+			cmdLine: "--log_output_level warn --log_config " + yamlFile + " --log_rotate_max_age 3",
+			result: func(o *Options) {
+				o.ConfigFile = yamlFile
+				o.OutputPath = "stderr"
+				o.RotationMaxAge = 3
+				o.RotationMaxSizeBytes = Gibibyte
+				o.OutputLevel = "warn"
+				o.LogCaller = true
+			},
+		},
+		{
+			cmdLine: "--log_config " + jsonFile + " --log_as_json=false",
+			result: func(o *Options) {
+				o.ConfigFile = jsonFile
+				o.RotateOutputPath = "/tmp/log"
+				o.RotationMaxSizeBytes = 5 * Mebibyte
+			},
+		},
+		{
+			cmdLine: "--log_config " + badFile,
+			err:     "failed to parse log config file",
+		},
+		{
+			cmdLine: "--log_config " + filepath.Join(dir, "missing.yaml"),
+			err:     "failed to read log config file",
+		},
+	}
+
+	for i, c := range cases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			o := DefaultOptions()
+			cmd := &cobra.Command{}
+			o.AddFlags(cmd.Flags())
+			cmd.SetArgs(strings.Split(c.cmdLine, " "))
+			if err := cmd.Execute(); err != nil {
+				t.Fatalf("Got %v, expecting success", err)
+			}
+
+			err := o.Complete(cmd.Flags())
+			if c.err != "" {
+				if err == nil || !strings.Contains(err.Error(), c.err) {
+					t.Errorf("Got %v, expecting error containing '%s'", err, c.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Got %v, expecting success", err)
+			}
+
+			want := DefaultOptions()
+			c.result(want)
+			if !reflect.DeepEqual(*want, publicOptions(o)) {
+				t.Errorf("Got %v, expected %v", *o, *want)
+			}
+		})
+	}
+}
+
+func TestConfigureConfigFile(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "log.yaml")
+	_ = os.WriteFile(configFile, []byte("outputLevel: error\nlogCaller: true\n"), 0o644)
+	defer func() { _ = Configure(testOptions()) }()
+
+	o := testOptions()
+	cmd := &cobra.Command{}
+	o.AddFlags(cmd.Flags())
+	cmd.SetArgs([]string{"--log_config", configFile, "--log_output_level", "warn"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Got %v, expecting success", err)
+	}
+
+	// the file is loaded without calling Complete, with the flags taking precedence
+	if err := Configure(o); err != nil {
+		t.Fatalf("Got %v, expecting success", err)
+	}
+	if defaultLogger.GetOutputLevel() != WarnLevel || !defaultLogger.GetLogCallers() {
+		t.Errorf("Got output level %v and log callers %v, expecting the file and flags to be applied",
+			defaultLogger.GetOutputLevel(), defaultLogger.GetLogCallers())
+	}
+
+	o = testOptions()
+	o.ConfigFile = filepath.Join(t.TempDir(), "missing.yaml")
+	if err := Configure(o); err == nil || !strings.Contains(err.Error(), "failed to read log config file") {
+		t.Errorf("Got %v, expecting the config file to be read", err)
+	}
+}
+
+func TestOptionsEnv(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "log.yaml")
+	_ = os.WriteFile(configFile, []byte("outputPath: stderr\noutputLevel: error\nrotationMaxAge: 7\n"), 0o644)
+
+	cases := []struct {
+		env     map[string]string
+		prefix  string
+		cmdLine string
+		result  func(o *Options)
+		err     string
+	}{
+		{
+			env: map[string]string{"KUSION_LOG_OUTPUT_LEVEL": "debug", "KUSION_LOG_AS_JSON": "true"},
+			result: func(o *Options) {
+				o.OutputLevel = "debug"
+				o.JSONEncoding = true
+			},
+		},
+		{
+			env:     map[string]string{"KUSION_LOG_OUTPUT_LEVEL": "debug", "KUSION_LOG_ROTATE_MAX_AGE": "3"},
+			cmdLine: "--log_output_level warn",
+			result: func(o *Options) {
+				o.OutputLevel = "warn"
+				o.RotationMaxAge = 3
+			},
+		},
+		{
+			env: map[string]string{"KUSION_LOG_CONFIG": configFile, "KUSION_LOG_OUTPUT_LEVEL": "debug"},
+			result: func(o *Options) {
+				o.ConfigFile = configFile
+				o.OutputPath = "stderr"
+				o.OutputLevel = "debug"
+				o.RotationMaxAge = 7
+			},
+		},
+		{
+			env:     map[string]string{"KUSION_LOG_CONFIG": filepath.Join(dir, "missing.yaml"), "KUSION_LOG_OUTPUT_LEVEL": "debug"},
+			cmdLine: "--log_config " + configFile + " --log_rotate_max_age 1",
+			result: func(o *Options) {
+				o.ConfigFile = configFile
+				o.OutputPath = "stderr"
+				o.OutputLevel = "debug"
+				o.RotationMaxAge = 1
+			},
+		},
+		{
+			env:    map[string]string{"MYAPP_LOG_CALLER": "true", "KUSION_LOG_AS_JSON": "true"},
+			prefix: "MYAPP",
+			result: func(o *Options) {
+				o.EnvPrefix = "MYAPP"
+				o.LogCaller = true
+			},
+		},
+		{
+			env:    map[string]string{"KUSION_LOG_CALLER": "true"},
+			prefix: "-",
+			result: func(o *Options) {
+				o.EnvPrefix = ""
+			},
+		},
+		{
+			env: map[string]string{"KUSION_LOG_ROTATE_MAX_AGE": "forever"},
+			err: "invalid value 'forever' for environment variable KUSION_LOG_ROTATE_MAX_AGE",
+		},
+	}
+
+	for i, c := range cases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			for k, v := range c.env {
+				t.Setenv(k, v)
+			}
+
+			o := DefaultOptions()
+			switch c.prefix {
+			case "":
+			case "-":
+				o.EnvPrefix = ""
+			default:
+				o.EnvPrefix = c.prefix
+			}
+
+			cmd := &cobra.Command{}
+			o.AddFlags(cmd.Flags())
+			cmd.SetArgs(strings.Fields(c.cmdLine))
+			if err := cmd.Execute(); err != nil {
+				t.Fatalf("Got %v, expecting success", err)
+			}
+
+			err := o.Complete(cmd.Flags())
+			if c.err != "" {
+				if err == nil || !strings.Contains(err.Error(), c.err) {
+					t.Errorf("Got %v, expecting error containing '%s'", err, c.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Got %v, expecting success", err)
+			}
+
+			want := DefaultOptions()
+			c.result(want)
+			if !reflect.DeepEqual(*want, publicOptions(o)) {
+				t.Errorf("Got %v, expected %v", *o, *want)
+			}
+		})
+	}
+}
+
+func TestConfigureEnv(t *testing.T) {
+	t.Setenv("KUSION_LOG_OUTPUT_LEVEL", "error")
+	t.Setenv("KUSION_LOG_CALLER", "true")
+	defer func() { _ = Configure(testOptions()) }()
+
+	o := testOptions()
+	cmd := &cobra.Command{}
+	o.AddFlags(cmd.Flags())
+	cmd.SetArgs([]string{"--log_output_level", "warn"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Got %v, expecting success", err)
+	}
+
+	// the environment is applied without calling Complete, with the flags taking precedence
+	if err := Configure(o); err != nil {
+		t.Fatalf("Got %v, expecting success", err)
+	}
+	if defaultLogger.GetOutputLevel() != WarnLevel || !defaultLogger.GetLogCallers() {
+		t.Errorf("Got output level %v and log callers %v, expecting the environment and flags to be applied",
+			defaultLogger.GetOutputLevel(), defaultLogger.GetLogCallers())
+	}
+
+	// options not added to a flag set apply the environment too
+	if err := Configure(testOptions()); err != nil {
+		t.Fatalf("Got %v, expecting success", err)
+	}
+	if defaultLogger.GetOutputLevel() != ErrorLevel || !defaultLogger.GetLogCallers() {
+		t.Errorf("Got output level %v and log callers %v, expecting the environment to be applied",
+			defaultLogger.GetOutputLevel(), defaultLogger.GetLogCallers())
+	}
+
+	// but not to options already completed
+	o = testOptions()
+	o.EnvPrefix = ""
+	if err := o.Complete(nil); err != nil {
+		t.Fatalf("Got %v, expecting success", err)
+	}
+	o.EnvPrefix = DefaultEnvPrefix
+	if err := Configure(o); err != nil {
+		t.Fatalf("Got %v, expecting success", err)
+	}
+	if defaultLogger.GetLogCallers() {
+		t.Error("Got callers logged, expecting the environment to be left to Complete")
+	}
+}
+
+func TestOptionsEnvUsage(t *testing.T) {
+	o := DefaultOptions()
+	cmd := &cobra.Command{}
+	o.AddFlags(cmd.Flags())
+
+	if usage := cmd.Flags().Lookup("log_output_level").Usage; !strings.HasSuffix(usage, "(env KUSION_LOG_OUTPUT_LEVEL)") {
+		t.Errorf("Got usage '%s', expecting it to name the environment variable", usage)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	dir := t.TempDir()
+	notDir := filepath.Join(dir, "file")
+	_ = os.WriteFile(notDir, nil, 0o644)
+	readOnly := filepath.Join(dir, "read-only")
+	_ = os.WriteFile(readOnly, nil, 0o444)
+	readOnlyDir := filepath.Join(dir, "read-only-dir")
+	_ = os.Mkdir(readOnlyDir, 0o555)
+
+	cases := []struct {
+		name   string
+		modify func(o *Options)
+		errs   []string
+	}{
+		{
+			name:   "defaults",
+			modify: func(o *Options) {},
+		},
+		{
+			name: "valid paths",
+			modify: func(o *Options) {
+				o.OutputPath = filepath.Join(dir, "out.log")
+				o.RotateOutputPath = filepath.Join(dir, "missing", "rotate.log")
+				o.ErrorOutputPath = notDir
+			},
+		},
+		{
+			name: "all invalid",
+			modify: func(o *Options) {
+				o.OutputLevel = "engine:debug,verbose"
+				o.StackTraceLevel = "no scope:error"
+				o.OutputPath = filepath.Join(notDir, "out.log")
+				o.ErrorOutputPath = filepath.Join(dir, "missing", "err.log")
+				o.RotationMaxSizeBytes = -1
+				o.RotationMaxAge = -2
+				o.RotationMaxBackups = -3
+			},
+			errs: []string{
+				"invalid output level: invalid level 'verbose'",
+				"invalid stack trace level: invalid scope 'no scope'",
+				"invalid output path: stat " + filepath.Join(notDir, "out.log") + ": not a directory",
+				"invalid error output path: stat " + filepath.Join(dir, "missing"),
+				"invalid rotation max size -1, must not be negative",
+				"invalid rotation max age -2, must not be negative",
+				"invalid rotation max backups -3, must not be negative",
+			},
+		},
+		{
+			name: "read-only paths",
+			modify: func(o *Options) {
+				o.OutputPath = readOnly
+				o.RotateOutputPath = filepath.Join(readOnlyDir, "missing", "rotate.log")
+				o.ErrorOutputPath = dir
+			},
+			errs: []string{
+				"invalid output path: file '" + readOnly + "' is not writable",
+				"invalid error output path: '" + dir + "' is a directory",
+				"invalid rotating output path: directory '" + readOnlyDir + "' is not writable",
+			},
+		},
+		{
+			name: "empty output level",
+			modify: func(o *Options) {
+				o.OutputLevel = ""
+				o.StackTraceLevel = ""
+			},
+			errs: []string{"invalid output level: empty level, must be one of [debug info warn error fatal none]"},
+		},
+		{
+			name: "time-based rotation",
+			modify: func(o *Options) {
+				o.RotationInterval = RotateDaily
+				o.RotationOffset = "2h30m"
+				o.RotationTimeZone = "UTC"
+				o.RotationBackupFormat = "{name}{ext}.2006-01-02"
+			},
+		},
+		{
+			name: "invalid time-based rotation",
+			modify: func(o *Options) {
+				o.RotationInterval = "weekly"
+				o.RotationOffset = "1h"
+				o.RotationTimeZone = "Nowhere/Special"
+				o.RotationBackupFormat = "{ext}-2006"
+				o.RotationCompress = "bzip2"
+			},
+			errs: []string{
+				"invalid rotation interval 'weekly', must be one of [hourly daily]",
+				"invalid rotation offset '1h', a rotation interval must be set",
+				"invalid rotation time zone: unknown time zone Nowhere/Special",
+				"invalid rotation compression 'bzip2', must be one of [gzip zstd]",
+				"invalid rotation backup format '{ext}-2006': the {name} placeholder is missing",
+			},
+		},
+		{
+			name: "rotation offset beyond interval",
+			modify: func(o *Options) {
+				o.RotationInterval = RotateHourly
+				o.RotationOffset = "1h"
+			},
+			errs: []string{"invalid rotation offset '1h', must be within the hourly rotation interval"},
+		},
+		{
+			name: "invalid async",
+			modify: func(o *Options) {
+				o.Async = true
+				o.AsyncQueueSize = 0
+				o.AsyncBufferSize = -1
+				o.AsyncFlushInterval = "0s"
+				o.AsyncOverflow = "spill"
+			},
+			errs: []string{
+				"invalid async queue size 0, must be positive",
+				"invalid async buffer size -1, must not be negative",
+				"invalid async flush interval '0s', must be positive",
+				"invalid async overflow policy 'spill', must be one of [block drop_oldest drop_newest]",
+			},
+		},
+		{
+			name: "sampling",
+			modify: func(o *Options) {
+				o.Sampling = "100/10,engine:none"
+				o.SamplingInterval = "10s"
+			},
+		},
+		{
+			name: "invalid sampling",
+			modify: func(o *Options) {
+				o.Sampling = "100-10"
+			},
+			errs: []string{"invalid sampling: invalid sampling '100-10', must be <first>/<thereafter> or none"},
+		},
+		{
+			name: "invalid sampling interval",
+			modify: func(o *Options) {
+				o.Sampling = "100/10"
+				o.SamplingInterval = "-1s"
+			},
+			errs: []string{"invalid sampling interval '-1s', must be positive"},
+		},
+		{
+			name: "invalid pretty encoding",
+			modify: func(o *Options) {
+				o.JSONEncoding = true
+				o.PrettyEncoding = true
+				o.Color = "sometimes"
+				o.PrettyTimestamps = "utc"
+			},
+			errs: []string{
+				"the JSON and pretty encodings cannot be combined",
+				"invalid color 'sometimes', must be one of [auto always never]",
+				"invalid pretty timestamps 'utc', must be one of [local relative]",
+			},
+		},
+		{
+			name: "encoder",
+			modify: func(o *Options) {
+				o.TimeFormat = "15:04"
+				o.TimeZone = "Local"
+				o.EncoderKeys = "time:@timestamp,msg:message,stack:"
+			},
+		},
+		{
+			name: "invalid encoder",
+			modify: func(o *Options) {
+				o.TimeFormat = "Z07:00"
+				o.TimeZone = "Nowhere/Special"
+				o.LevelCase = "title"
+				o.CallerFormat = "long"
+				o.EncoderKeys = "time:ts,func:function,msg,level:ts"
+			},
+			errs: []string{
+				"invalid time zone: unknown time zone Nowhere/Special",
+				"invalid time format 'Z07:00', must be one of [rfc3339micro rfc3339 rfc3339nano epoch_millis] or a Go time layout",
+				"invalid level case 'title', must be one of [lower upper]",
+				"invalid caller format 'long', must be one of [short full]",
+				"invalid encoder key 'func', must be one of [time level scope caller msg stack]",
+				"invalid encoder key 'msg', must be in the form <key>:<name>",
+				"invalid encoder keys, time and level are both named 'ts'",
+			},
+		},
+		{
+			name: "format alias",
+			modify: func(o *Options) {
+				o.Format = JSONEncoding
+				o.JSONEncoding = true
+			},
+		},
+		{
+			name: "invalid format",
+			modify: func(o *Options) {
+				o.Format = "xml"
+			},
+			errs: []string{"invalid format 'xml', must be one of [console json pretty logfmt ecs otel]"},
+		},
+		{
+			name: "format conflicting with alias",
+			modify: func(o *Options) {
+				o.Format = OTelEncoding
+				o.JSONEncoding = true
+			},
+			errs: []string{"the json encoding cannot be combined with the otel format"},
+		},
+		{
+			name: "no output",
+			modify: func(o *Options) {
+				o.OutputPath = ""
+			},
+			errs: []string{"no log output configured"},
+		},
+		{
+			name: "same rotating path",
+			modify: func(o *Options) {
+				o.OutputPath = filepath.Join(dir, "out.log")
+				o.RotateOutputPath = filepath.Join(dir, ".", "out.log")
+			},
+			errs: []string{"the rotating output path '" + filepath.Join(dir, "out.log") + "' must differ from the output path"},
+		},
+	}
+
+	RegisterScope("engine", "")
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			o := DefaultOptions()
+			c.modify(o)
+
+			err := o.Validate()
+			if len(c.errs) == 0 {
+				if err != nil {
+					t.Errorf("Got %v, expecting success", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Got success, expecting errors")
+			}
+
+			lines := strings.Split(err.Error(), "\n")
+			if len(lines) != len(c.errs) {
+				t.Fatalf("Got %d errors %q, expecting %d", len(lines), lines, len(c.errs))
+			}
+			for i, want := range c.errs {
+				if !strings.HasPrefix(lines[i], want) {
+					t.Errorf("Got error '%s', expecting prefix '%s'", lines[i], want)
+				}
+			}
+
+			if err := Configure(o); err == nil || err.Error() != o.Validate().Error() {
+				t.Errorf("Got %v from Configure, expecting the Validate errors", err)
+			}
+		})
+	}
+}
+
+func TestValidateReadOnly(t *testing.T) {
+	dir := t.TempDir()
+
+	o := DefaultOptions()
+	o.OutputPath = filepath.Join(dir, "out.log")
+	o.RotateOutputPath = filepath.Join(dir, "missing", "rotate.log")
+	if err := o.Validate(); err != nil {
+		t.Fatalf("Got %v, expecting success", err)
+	}
+
+	// validating doesn't create the files nor their directories
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Got %v in the directory, expecting validation not to write to the file system", entries)
+	}
+}
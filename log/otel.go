@@ -0,0 +1,154 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"bytes"
+	"strconv"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// otelSeverityNumbers maps levels to the severity numbers of the OpenTelemetry log data
+// model.
+var otelSeverityNumbers = map[zapcore.Level]int{
+	zapcore.DebugLevel:  5,
+	zapcore.InfoLevel:   9,
+	zapcore.WarnLevel:   13,
+	zapcore.ErrorLevel:  17,
+	zapcore.DPanicLevel: 21,
+	zapcore.PanicLevel:  21,
+	zapcore.FatalLevel:  21,
+}
+
+var otelPool = buffer.NewPool()
+
+// otelEncoder formats entries as JSON following the OpenTelemetry log data model, such
+// as:
+//
+//	{"Timestamp":"1714557845123456000","SeverityText":"INFO","SeverityNumber":9,"Body":"applying resources",
+//	"InstrumentationScope":{"Name":"engine"},"Attributes":{"count":3}}
+//
+// The scope is written as the instrumentation scope, and the trace_id and span_id fields
+// as TraceId and SpanId. Other fields are written as attributes, along with the caller,
+// stack trace and error named after the OpenTelemetry semantic conventions.
+type otelEncoder struct {
+	// Encoder accumulates the attributes as JSON.
+	zapcore.Encoder
+	// top encodes the top-level fields of entries.
+	top zapcore.Encoder
+	// traceID and spanID are set by the trace_id and span_id context fields.
+	traceID, spanID string
+}
+
+func newOTelEncoder() zapcore.Encoder {
+	return &otelEncoder{
+		Encoder: zapcore.NewJSONEncoder(zapcore.EncoderConfig{
+			EncodeDuration: zapcore.NanosDurationEncoder,
+			EncodeTime:     zapcore.RFC3339NanoTimeEncoder,
+		}),
+		top: zapcore.NewJSONEncoder(zapcore.EncoderConfig{}),
+	}
+}
+
+func (e *otelEncoder) Clone() zapcore.Encoder {
+	return &otelEncoder{Encoder: e.Encoder.Clone(), top: e.top, traceID: e.traceID, spanID: e.spanID}
+}
+
+// AddString writes the trace_id and span_id context fields as TraceId and SpanId.
+func (e *otelEncoder) AddString(key, value string) {
+	switch key {
+	case TraceIDField:
+		e.traceID = value
+	case SpanIDField:
+		e.spanID = value
+	default:
+		e.Encoder.AddString(key, value)
+	}
+}
+
+func (e *otelEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	traceID, spanID := e.traceID, e.spanID
+	attributes := make([]zapcore.Field, 0, len(fields)+4)
+	if ent.Caller.Defined {
+		attributes = append(attributes, zap.String("code.filepath", ent.Caller.File), zap.Int("code.lineno", ent.Caller.Line))
+		if ent.Caller.Function != "" {
+			attributes = append(attributes, zap.String("code.function", ent.Caller.Function))
+		}
+	}
+	for _, f := range fields {
+		switch {
+		case f.Key == TraceIDField && f.Type == zapcore.StringType:
+			traceID = f.String
+			continue
+		case f.Key == SpanIDField && f.Type == zapcore.StringType:
+			spanID = f.String
+			continue
+		case f.Key == "error" && f.Type == zapcore.ErrorType:
+			f = zap.String("exception.message", f.Interface.(error).Error())
+		}
+		attributes = append(attributes, f)
+	}
+	if ent.Stack != "" {
+		attributes = append(attributes, zap.String("exception.stacktrace", ent.Stack))
+	}
+
+	// encoding an empty entry only encodes the attributes
+	encoded, err := e.Encoder.EncodeEntry(zapcore.Entry{}, attributes)
+	if err != nil {
+		return nil, err
+	}
+	defer encoded.Free()
+
+	top := make([]zapcore.Field, 0, 7)
+	top = append(top,
+		zap.String("Timestamp", strconv.FormatInt(ent.Time.UnixNano(), 10)),
+		zap.String("SeverityText", ent.Level.CapitalString()),
+		zap.Int("SeverityNumber", otelSeverityNumbers[ent.Level]),
+		zap.String("Body", ent.Message),
+	)
+	if ent.LoggerName != "" {
+		top = append(top, zap.Object("InstrumentationScope", otelScope(ent.LoggerName)))
+	}
+	if traceID != "" {
+		top = append(top, zap.String("TraceId", traceID))
+	}
+	if spanID != "" {
+		top = append(top, zap.String("SpanId", spanID))
+	}
+	encodedTop, err := e.top.EncodeEntry(zapcore.Entry{}, top)
+	if err != nil {
+		return nil, err
+	}
+	defer encodedTop.Free()
+
+	// the attributes are appended to the top-level object, in place of its closing brace
+	buf := otelPool.Get()
+	buf.Write(bytes.TrimSuffix(bytes.TrimSpace(encodedTop.Bytes()), []byte("}")))
+	buf.AppendString(`,"Attributes":`)
+	buf.Write(bytes.TrimSpace(encoded.Bytes()))
+	buf.AppendString("}\n")
+	return buf, nil
+}
+
+// otelScope encodes a scope name as an OpenTelemetry instrumentation scope.
+type otelScope string
+
+func (s otelScope) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("Name", string(s))
+	return nil
+}
@@ -0,0 +1,251 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// ColorAuto colors pretty output written to stdout or stderr when it is a terminal,
+	// unless the NO_COLOR environment variable is set.
+	ColorAuto = "auto"
+	// ColorAlways always colors pretty output.
+	ColorAlways = "always"
+	// ColorNever never colors pretty output.
+	ColorNever = "never"
+)
+
+var colorListString = []string{ColorAuto, ColorAlways, ColorNever}
+
+const (
+	// TimestampsLocal prefixes pretty output with the local time of day.
+	TimestampsLocal = "local"
+	// TimestampsRelative prefixes pretty output with the time elapsed since the process
+	// started.
+	TimestampsRelative = "relative"
+)
+
+var timestampsListString = []string{TimestampsLocal, TimestampsRelative}
+
+// processStart is the time relative timestamps are measured from.
+var processStart = time.Now()
+
+// ANSI escape sequences used by the pretty encoder.
+const (
+	ansiReset = "\x1b[0m"
+	ansiDim   = "\x1b[2m"
+	ansiCyan  = "\x1b[36m"
+)
+
+var levelColors = map[zapcore.Level]string{
+	zapcore.DebugLevel:  "\x1b[35m",
+	zapcore.InfoLevel:   "\x1b[34m",
+	zapcore.WarnLevel:   "\x1b[33m",
+	zapcore.ErrorLevel:  "\x1b[31m",
+	zapcore.DPanicLevel: "\x1b[1;31m",
+	zapcore.PanicLevel:  "\x1b[1;31m",
+	zapcore.FatalLevel:  "\x1b[1;31m",
+}
+
+var prettyPool = buffer.NewPool()
+
+// useColor returns whether pretty output written to the path should be colored.
+func useColor(color, path string) bool {
+	switch color {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+
+	switch path {
+	case "stdout":
+		return isTerminal(os.Stdout)
+	case "stderr":
+		return isTerminal(os.Stderr)
+	}
+	return false
+}
+
+// isTerminal returns whether f is a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// prettyEncoder formats entries for people reading them in a terminal rather than for
+// machines. Entries start with a timestamp, a capitalized level and the scope, aligned
+// across scopes, followed by the message. Fields and stack traces follow on their own
+// indented lines, with multi-line strings kept readable.
+type prettyEncoder struct {
+	// Encoder accumulates the context fields as JSON, so that fields of any type can
+	// be listed in the order they were added.
+	zapcore.Encoder
+
+	color    bool
+	relative bool
+}
+
+func newPrettyEncoder(color bool, timestamps string) zapcore.Encoder {
+	return &prettyEncoder{
+		Encoder: zapcore.NewJSONEncoder(zapcore.EncoderConfig{
+			EncodeDuration: zapcore.StringDurationEncoder,
+			EncodeTime:     zapcore.ISO8601TimeEncoder,
+		}),
+		color:    color,
+		relative: timestamps == TimestampsRelative,
+	}
+}
+
+func (e *prettyEncoder) Clone() zapcore.Encoder {
+	clone := *e
+	clone.Encoder = e.Encoder.Clone()
+	return &clone
+}
+
+func (e *prettyEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	// encoding an empty entry only encodes the fields
+	encoded, err := e.Encoder.EncodeEntry(zapcore.Entry{}, fields)
+	if err != nil {
+		return nil, err
+	}
+	defer encoded.Free()
+
+	buf := prettyPool.Get()
+
+	if e.relative {
+		e.colored(buf, ansiDim, "+"+strconv.FormatFloat(ent.Time.Sub(processStart).Seconds(), 'f', 3, 64)+"s")
+	} else {
+		e.colored(buf, ansiDim, ent.Time.Local().Format("15:04:05.000"))
+	}
+
+	buf.AppendByte(' ')
+	level := ent.Level.CapitalString()
+	e.colored(buf, levelColors[ent.Level], level)
+	pad(buf, 5-len(level))
+
+	if width := int(scopeNameWidth.Load()); width > 0 {
+		buf.AppendByte(' ')
+		e.colored(buf, ansiDim, ent.LoggerName)
+		pad(buf, width-len(ent.LoggerName))
+	}
+
+	if ent.Caller.Defined {
+		buf.AppendByte(' ')
+		e.colored(buf, ansiDim, ent.Caller.TrimmedPath())
+	}
+
+	buf.AppendByte(' ')
+	appendIndented(buf, ent.Message, "    ")
+
+	if pairs, err := decodeFields(encoded.Bytes()); err != nil {
+		// should not happen, but show the fields as they are rather than losing them
+		buf.AppendByte(' ')
+		buf.Write(bytes.TrimSpace(encoded.Bytes()))
+	} else {
+		for _, p := range pairs {
+			buf.AppendString("\n    ")
+			e.colored(buf, ansiCyan, p.key)
+			buf.AppendByte(':')
+
+			var s string
+			if json.Unmarshal(p.value, &s) != nil {
+				buf.AppendByte(' ')
+				buf.Write(p.value)
+			} else if strings.Contains(s, "\n") {
+				buf.AppendString("\n        ")
+				appendIndented(buf, strings.TrimRight(s, "\n"), "        ")
+			} else {
+				buf.AppendByte(' ')
+				buf.AppendString(s)
+			}
+		}
+	}
+
+	if ent.Stack != "" {
+		for _, line := range strings.Split(ent.Stack, "\n") {
+			buf.AppendString("\n    ")
+			e.colored(buf, ansiDim, line)
+		}
+	}
+
+	buf.AppendByte('\n')
+	return buf, nil
+}
+
+// colored appends s to buf, in the color given by the ANSI escape sequence if enabled.
+func (e *prettyEncoder) colored(buf *buffer.Buffer, color string, s string) {
+	if !e.color || color == "" {
+		buf.AppendString(s)
+		return
+	}
+	buf.AppendString(color)
+	buf.AppendString(s)
+	buf.AppendString(ansiReset)
+}
+
+// appendIndented appends s to buf, indenting every line but the first.
+func appendIndented(buf *buffer.Buffer, s string, indent string) {
+	buf.AppendString(strings.ReplaceAll(s, "\n", "\n"+indent))
+}
+
+func pad(buf *buffer.Buffer, n int) {
+	for ; n > 0; n-- {
+		buf.AppendByte(' ')
+	}
+}
+
+// prettyField is a field decoded from its JSON encoding.
+type prettyField struct {
+	key   string
+	value json.RawMessage
+}
+
+// decodeFields decodes the fields of a JSON object, in order.
+func decodeFields(p []byte) ([]prettyField, error) {
+	dec := json.NewDecoder(bytes.NewReader(p))
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+
+	var fields []prettyField
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := t.(string)
+
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		fields = append(fields, prettyField{key: key, value: value})
+	}
+	return fields, nil
+}
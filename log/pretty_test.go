@@ -0,0 +1,124 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestPrettyEncoder(t *testing.T) {
+	RegisterScope("engine", "")
+	width := int(scopeNameWidth.Load())
+
+	ent := zapcore.Entry{
+		Level:      zapcore.WarnLevel,
+		Time:       time.Date(2024, 5, 1, 10, 4, 5, 123456789, time.UTC),
+		LoggerName: "engine",
+		Message:    "applying\nresources",
+		Stack:      "main.main\n\tmain.go:10",
+	}
+	fields := []zapcore.Field{
+		zap.Int("count", 3),
+		zap.String("diff", "- a\n+ b\n"),
+		zap.Any("labels", map[string]string{"app": "web"}),
+	}
+
+	enc := newPrettyEncoder(false, TimestampsLocal)
+	enc.AddString("request", "r1")
+	buf, err := enc.EncodeEntry(ent, fields)
+	if err != nil {
+		t.Fatalf("Got error '%v', expected success", err)
+	}
+
+	expected := ent.Time.Local().Format("15:04:05.000") + " WARN  engine" + strings.Repeat(" ", width-len("engine")) + " applying\n" +
+		"    resources\n" +
+		"    request: r1\n" +
+		"    count: 3\n" +
+		"    diff:\n" +
+		"        - a\n" +
+		"        + b\n" +
+		`    labels: {"app":"web"}` + "\n" +
+		"    main.main\n" +
+		"    \tmain.go:10\n"
+	if got := buf.String(); got != expected {
+		t.Errorf("Got %q, expected %q", got, expected)
+	}
+}
+
+func TestPrettyEncoderColor(t *testing.T) {
+	enc := newPrettyEncoder(true, TimestampsRelative)
+	buf, err := enc.EncodeEntry(zapcore.Entry{Level: zapcore.ErrorLevel, Time: processStart.Add(1500 * time.Millisecond), Message: "failed"},
+		[]zapcore.Field{zap.String("resource", "web")})
+	if err != nil {
+		t.Fatalf("Got error '%v', expected success", err)
+	}
+
+	got := buf.String()
+	for _, want := range []string{"\x1b[2m+1.500s\x1b[0m ", "\x1b[31mERROR\x1b[0m", "\x1b[36mresource\x1b[0m: web\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Got %q, expecting it to contain %q", got, want)
+		}
+	}
+}
+
+func TestUseColor(t *testing.T) {
+	cases := []struct {
+		color    string
+		path     string
+		noColor  string
+		expected bool
+	}{
+		{ColorAlways, "out.log", "1", true},
+		{ColorNever, "stdout", "", false},
+		{ColorAuto, "out.log", "", false},
+		{ColorAuto, "stdout", "1", false},
+	}
+
+	for _, c := range cases {
+		t.Setenv("NO_COLOR", c.noColor)
+		if got := useColor(c.color, c.path); got != c.expected {
+			t.Errorf("Got %v for %s output to %s with NO_COLOR=%q, expected %v", got, c.color, c.path, c.noColor, c.expected)
+		}
+	}
+}
+
+func TestConfigurePretty(t *testing.T) {
+	lines, err := captureStdout(func() {
+		o := testOptions()
+		o.PrettyEncoding = true
+		o.Color = ColorAuto
+		if err := Configure(o); err != nil {
+			t.Errorf("Got err '%v', expecting success", err)
+		}
+
+		Info("pretty")
+		_ = Sync()
+		_ = Configure(testOptions())
+	})
+	if err != nil {
+		t.Fatalf("Got error '%v', expected success", err)
+	}
+
+	// output redirected to a file isn't colored
+	if match, _ := regexp.MatchString(`^\d\d:\d\d:\d\d\.\d{3} INFO  +pretty$`, lines[0]); !match {
+		t.Errorf("Got '%s', expecting a pretty entry", lines[0])
+	}
+}
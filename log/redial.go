@@ -0,0 +1,107 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// minRedialBackoff is the delay before dialing again after the first failed dial.
+	minRedialBackoff = 100 * time.Millisecond
+	// maxRedialBackoff limits the delay between dials while the daemon stays unreachable.
+	maxRedialBackoff = 30 * time.Second
+)
+
+// redialConn is a connection to a logging daemon, such as syslog or journald, that is
+// dialed again when a write fails, in case the daemon was restarted. After a failed dial,
+// writes fail right away until the backoff delay passes, which doubles with each failed
+// dial, so that a daemon which is down doesn't slow down every entry.
+type redialConn struct {
+	daemon  string
+	network string
+	address string
+
+	mu      sync.Mutex
+	conn    net.Conn
+	backoff time.Duration
+	retry   time.Time
+	err     error
+}
+
+// dialRedialConn connects to the named daemon at the address.
+func dialRedialConn(daemon, network, address string) (*redialConn, error) {
+	c := &redialConn{
+		daemon:  daemon,
+		network: network,
+		address: address,
+	}
+	if err := c.dial(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// dial connects to the daemon, unless the previous dial failed less than the backoff
+// delay ago. It must be called with the lock held.
+func (c *redialConn) dial() error {
+	if time.Now().Before(c.retry) {
+		return c.err
+	}
+
+	conn, err := net.Dial(c.network, c.address)
+	if err != nil {
+		c.backoff = min(max(2*c.backoff, minRedialBackoff), maxRedialBackoff)
+		c.retry = time.Now().Add(c.backoff)
+		c.err = fmt.Errorf("failed to connect to %s at %s %s: %v", c.daemon, c.network, c.address, err)
+		return c.err
+	}
+	c.conn, c.backoff, c.retry, c.err = conn, 0, time.Time{}, nil
+	return nil
+}
+
+// Write sends p as a single message, dialing again once if the connection failed.
+func (c *redialConn) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn != nil {
+		if n, err := c.conn.Write(p); err == nil {
+			return n, nil
+		}
+		_ = c.conn.Close()
+		c.conn = nil
+	}
+	if err := c.dial(); err != nil {
+		return 0, err
+	}
+	return c.conn.Write(p)
+}
+
+// Close closes the connection to the daemon.
+func (c *redialConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
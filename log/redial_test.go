@@ -0,0 +1,118 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !windows
+
+package log
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func listenUnixgram(t *testing.T, path string) *net.UnixConn {
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("Got error '%v', expected success", err)
+	}
+	return conn
+}
+
+func readMessage(t *testing.T, conn *net.UnixConn) string {
+	buf := make([]byte, 1024)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("Got error '%v', expected a message", err)
+	}
+	return string(buf[:n])
+}
+
+func TestRedialConn(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "daemon.sock")
+	daemon := listenUnixgram(t, path)
+
+	c, err := dialRedialConn("daemon", "unixgram", path)
+	if err != nil {
+		t.Fatalf("Got error '%v', expected success", err)
+	}
+	defer c.Close()
+
+	if _, err := c.Write([]byte("first")); err != nil {
+		t.Fatalf("Got error '%v', expected success", err)
+	}
+	if got := readMessage(t, daemon); got != "first" {
+		t.Errorf("Got %q, expected %q", got, "first")
+	}
+
+	// restart the daemon, the next write dials again
+	_ = daemon.Close()
+	_ = os.Remove(path)
+	daemon = listenUnixgram(t, path)
+	defer daemon.Close()
+
+	if _, err := c.Write([]byte("second")); err != nil {
+		t.Fatalf("Got error '%v', expected the connection to be dialed again", err)
+	}
+	if got := readMessage(t, daemon); got != "second" {
+		t.Errorf("Got %q, expected %q", got, "second")
+	}
+}
+
+func TestRedialConnBackoff(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "daemon.sock")
+	if _, err := dialRedialConn("daemon", "unixgram", path); err == nil ||
+		!strings.Contains(err.Error(), "failed to connect to daemon at unixgram") {
+		t.Fatalf("Got %v, expecting a connection error", err)
+	}
+
+	daemon := listenUnixgram(t, path)
+	c, err := dialRedialConn("daemon", "unixgram", path)
+	if err != nil {
+		t.Fatalf("Got error '%v', expected success", err)
+	}
+	defer c.Close()
+
+	// stop the daemon, so that writing and dialing again both fail
+	_ = daemon.Close()
+	_ = os.Remove(path)
+	if _, err := c.Write([]byte("lost")); err == nil {
+		t.Fatal("Got success, expecting an error while the daemon is down")
+	}
+	if c.backoff != minRedialBackoff {
+		t.Errorf("Got backoff %v, expecting %v", c.backoff, minRedialBackoff)
+	}
+
+	// the daemon is back, but writes fail until the backoff delay passes
+	daemon = listenUnixgram(t, path)
+	defer daemon.Close()
+	if _, err := c.Write([]byte("early")); err == nil {
+		t.Error("Got success, expecting the backoff to delay dialing again")
+	}
+
+	c.retry = time.Now()
+	if _, err := c.Write([]byte("later")); err != nil {
+		t.Fatalf("Got error '%v', expected success once the backoff passed", err)
+	}
+	if got := readMessage(t, daemon); got != "later" {
+		t.Errorf("Got %q, expected %q", got, "later")
+	}
+	if c.backoff != 0 {
+		t.Errorf("Got backoff %v, expecting it to be reset", c.backoff)
+	}
+}
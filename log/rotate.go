@@ -0,0 +1,584 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// RotateHourly rotates log files at the start of every hour.
+	RotateHourly = "hourly"
+	// RotateDaily rotates log files at the start of every day.
+	RotateDaily = "daily"
+)
+
+var rotationIntervalListString = []string{RotateHourly, RotateDaily}
+
+const (
+	// CompressGzip compresses log file backups with gzip.
+	CompressGzip = "gzip"
+	// CompressZstd compresses log file backups with zstd.
+	CompressZstd = "zstd"
+)
+
+var compressionListString = []string{CompressGzip, CompressZstd}
+
+// compressionExtensions are the extensions appended to the names of compressed backups.
+var compressionExtensions = map[string]string{
+	CompressGzip: ".gz",
+	CompressZstd: ".zst",
+}
+
+// rotation holds the rotation settings parsed from the options.
+type rotation struct {
+	maxSize    int64
+	maxAge     time.Duration
+	maxBackups int
+	interval   string
+	offset     time.Duration
+	location   *time.Location
+	format     backupFormat
+	compress   string
+}
+
+// rotation parses the rotation settings, returning every problem found.
+func (o *Options) rotation() (*rotation, []error) {
+	r := &rotation{
+		maxSize:    int64(o.RotationMaxSizeBytes),
+		maxAge:     time.Duration(o.RotationMaxAge) * 24 * time.Hour,
+		maxBackups: o.RotationMaxBackups,
+		interval:   o.RotationInterval,
+		location:   time.Local,
+		compress:   o.RotationCompress,
+	}
+	var errs []error
+
+	var period time.Duration
+	switch o.RotationInterval {
+	case "":
+	case RotateHourly:
+		period = time.Hour
+	case RotateDaily:
+		period = 24 * time.Hour
+	default:
+		errs = append(errs, fmt.Errorf("invalid rotation interval '%s', must be one of %s", o.RotationInterval, rotationIntervalListString))
+	}
+
+	if o.RotationOffset != "" {
+		offset, err := time.ParseDuration(o.RotationOffset)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid rotation offset: %v", err))
+		} else if period == 0 {
+			errs = append(errs, fmt.Errorf("invalid rotation offset '%s', a rotation interval must be set", o.RotationOffset))
+		} else if offset < 0 || offset >= period {
+			errs = append(errs, fmt.Errorf("invalid rotation offset '%s', must be within the %s rotation interval", o.RotationOffset, o.RotationInterval))
+		}
+		r.offset = offset
+	}
+
+	if o.RotationTimeZone != "" {
+		location, err := time.LoadLocation(o.RotationTimeZone)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid rotation time zone: %v", err))
+		}
+		r.location = location
+	}
+
+	if _, ok := compressionExtensions[o.RotationCompress]; !ok && o.RotationCompress != "" {
+		errs = append(errs, fmt.Errorf("invalid rotation compression '%s', must be one of %s", o.RotationCompress, compressionListString))
+	}
+
+	format := o.RotationBackupFormat
+	if format == "" {
+		format = DefaultRotationBackupFormat
+	}
+	var err error
+	if r.format, err = parseBackupFormat(format); err != nil {
+		errs = append(errs, fmt.Errorf("invalid rotation backup format '%s': %v", format, err))
+	}
+
+	return r, errs
+}
+
+// periodStart returns the start of the rotation interval containing t, or the zero time
+// if there is no rotation interval.
+func (r *rotation) periodStart(t time.Time) time.Time {
+	t = t.In(r.location)
+	year, month, day := t.Date()
+
+	switch r.interval {
+	case RotateHourly:
+		start := time.Date(year, month, day, t.Hour(), 0, 0, 0, r.location).Add(r.offset)
+		if start.After(t) {
+			start = start.Add(-time.Hour)
+		}
+		return start
+	case RotateDaily:
+		start := time.Date(year, month, day, 0, 0, 0, 0, r.location).Add(r.offset)
+		if start.After(t) {
+			start = time.Date(year, month, day-1, 0, 0, 0, 0, r.location).Add(r.offset)
+		}
+		return start
+	}
+	return time.Time{}
+}
+
+// nextBoundary returns the end of the rotation interval starting at start.
+func (r *rotation) nextBoundary(start time.Time) time.Time {
+	switch r.interval {
+	case RotateHourly:
+		return start.Add(time.Hour)
+	case RotateDaily:
+		// go through the date so that days lasting 23 or 25 hours are handled
+		year, month, day := start.Add(-r.offset).Date()
+		return time.Date(year, month, day+1, 0, 0, 0, 0, r.location).Add(r.offset)
+	}
+	return time.Time{}
+}
+
+// backupFormat is a parsed backup naming pattern, made of a time layout surrounded by
+// placeholders.
+type backupFormat struct {
+	prefix string
+	layout string
+	suffix string
+}
+
+var backupPlaceholders = []string{"{name}", "{ext}"}
+
+func parseBackupFormat(format string) (backupFormat, error) {
+	f := backupFormat{layout: format}
+
+	for trimmed := true; trimmed; {
+		trimmed = false
+		for _, p := range backupPlaceholders {
+			if strings.HasPrefix(f.layout, p) {
+				f.prefix += p
+				f.layout = f.layout[len(p):]
+				trimmed = true
+			}
+			if strings.HasSuffix(f.layout, p) {
+				f.suffix = p + f.suffix
+				f.layout = f.layout[:len(f.layout)-len(p)]
+				trimmed = true
+			}
+		}
+	}
+
+	if !strings.Contains(format, "{name}") {
+		return f, errors.New("the {name} placeholder is missing")
+	}
+	for _, p := range backupPlaceholders {
+		if strings.Contains(f.layout, p) {
+			return f, fmt.Errorf("the %s placeholder must come before or after the time layout", p)
+		}
+	}
+	if f.layout == "" || (time.Time{}).Format(f.layout) == f.layout {
+		return f, errors.New("the time layout is missing")
+	}
+
+	return f, nil
+}
+
+// name returns the name of the n-th backup of the log file named base for time t.
+func (f backupFormat) name(base string, t time.Time, n int) string {
+	r := f.replacer(base)
+	name := r.Replace(f.prefix) + t.Format(f.layout)
+	if n > 0 {
+		name += "." + strconv.Itoa(n)
+	}
+	return name + r.Replace(f.suffix)
+}
+
+// parse returns the time and number of the backup of the log file named base with the
+// given name. It returns false if name isn't a backup of the log file.
+func (f backupFormat) parse(base string, name string, location *time.Location) (time.Time, int, bool) {
+	r := f.replacer(base)
+	prefix, suffix := r.Replace(f.prefix), r.Replace(f.suffix)
+	if name == base || len(name) <= len(prefix)+len(suffix) ||
+		!strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, suffix) {
+		return time.Time{}, 0, false
+	}
+
+	s := name[len(prefix) : len(name)-len(suffix)]
+	if t, err := time.ParseInLocation(f.layout, s, location); err == nil {
+		return t, 0, true
+	}
+
+	// the time may be followed by the number of the backup
+	i := strings.LastIndexByte(s, '.')
+	if i < 0 {
+		return time.Time{}, 0, false
+	}
+	n, err := strconv.Atoi(s[i+1:])
+	if err != nil || n <= 0 {
+		return time.Time{}, 0, false
+	}
+	t, err := time.ParseInLocation(f.layout, s[:i], location)
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+	return t, n, true
+}
+
+func (f backupFormat) replacer(base string) *strings.Replacer {
+	ext := filepath.Ext(base)
+	return strings.NewReplacer("{name}", strings.TrimSuffix(base, ext), "{ext}", ext)
+}
+
+// newRotatingSink returns a sink writing to a file rotated based on the options.
+func newRotatingSink(path string, options *Options) (Sink, error) {
+	r, errs := options.rotation()
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return &rotatingFile{rotation: r, path: path, now: time.Now}, nil
+}
+
+// rotatingFile is a log file that is rotated by size and time. It is opened when first
+// written to. After each rotation, backups exceeding the maximum count or age are deleted
+// and the others compressed in the background.
+type rotatingFile struct {
+	*rotation
+	path string
+	now  func() time.Time
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+	// start is the start of the current rotation interval, and next the end of it.
+	// Both are zero if there is no rotation interval.
+	start time.Time
+	next  time.Time
+
+	// milling is set while backups are processed in the background, and millPending
+	// when they need to be processed again. millErr is the last error doing so, which
+	// is returned by the next write.
+	milling     bool
+	millPending bool
+	millErr     error
+	millDone    sync.WaitGroup
+}
+
+// Write writes p to the file, rotating it first if it would grow too big or if a
+// rotation boundary was crossed.
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := f.now()
+	if f.file == nil {
+		if err := f.open(now); err != nil {
+			return 0, err
+		}
+	}
+
+	if (!f.next.IsZero() && !now.Before(f.next)) ||
+		(f.maxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.maxSize) {
+		if err := f.rotate(now); err != nil {
+			return 0, err
+		}
+		f.startMill()
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	if err != nil {
+		return n, err
+	}
+
+	err, f.millErr = f.millErr, nil
+	return n, err
+}
+
+// open opens the file for appending, creating it if needed. An existing file is assumed
+// to belong to the interval it was last written in, so that it is rotated right away if
+// that interval has already ended.
+func (f *rotatingFile) open(now time.Time) error {
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create log directory: %v", err)
+	}
+
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %v", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to open log file: %v", err)
+	}
+
+	f.file = file
+	f.size = info.Size()
+	if f.size > 0 {
+		now = info.ModTime()
+	}
+	f.start = f.periodStart(now)
+	f.next = f.nextBoundary(f.start)
+	return nil
+}
+
+// rotate renames the file to a new backup and opens a new file in its place.
+func (f *rotatingFile) rotate(now time.Time) error {
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("failed to close log file: %v", err)
+	}
+	f.file = nil
+
+	t := f.start
+	if t.IsZero() {
+		t = now
+	}
+	if err := os.Rename(f.path, f.backupPath(t)); err != nil {
+		return fmt.Errorf("failed to rotate log file: %v", err)
+	}
+
+	return f.open(now)
+}
+
+// backupPath returns a path for a new backup of time t that doesn't exist yet, whether
+// compressed or not.
+func (f *rotatingFile) backupPath(t time.Time) string {
+	dir, base := filepath.Split(f.path)
+	for n := 0; ; n++ {
+		path := filepath.Join(dir, f.format.name(base, t.In(f.location), n))
+		if !exists(path) && !exists(path+compressionExtensions[CompressGzip]) && !exists(path+compressionExtensions[CompressZstd]) {
+			return path
+		}
+	}
+}
+
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return !os.IsNotExist(err)
+}
+
+// backupFile is a backup of the log file, possibly compressed.
+type backupFile struct {
+	name       string
+	time       time.Time
+	n          int
+	compressed bool
+}
+
+// backups returns the backups of the log file, newest first.
+func (f *rotatingFile) backups() ([]backupFile, error) {
+	dir, base := filepath.Split(f.path)
+	entries, err := os.ReadDir(filepath.Clean(dir))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list log file backups: %v", err)
+	}
+
+	names := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		names[e.Name()] = true
+	}
+
+	var backups []backupFile
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+
+		name, compressed := e.Name(), false
+		for _, ext := range compressionExtensions {
+			if strings.HasSuffix(name, ext) {
+				name, compressed = strings.TrimSuffix(name, ext), true
+				break
+			}
+		}
+		if compressed && names[name] {
+			// the compression of the backup was interrupted, and will be done again
+			continue
+		}
+		if t, n, ok := f.format.parse(base, name, f.location); ok {
+			backups = append(backups, backupFile{name: e.Name(), time: t, n: n, compressed: compressed})
+		}
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		if backups[i].time.Equal(backups[j].time) {
+			return backups[i].n > backups[j].n
+		}
+		return backups[i].time.After(backups[j].time)
+	})
+	return backups, nil
+}
+
+// startMill processes backups in the background, unless that's already happening. It
+// must be called with the lock held.
+func (f *rotatingFile) startMill() {
+	f.millPending = true
+	if f.milling {
+		return
+	}
+
+	f.milling = true
+	f.millDone.Add(1)
+	go func() {
+		defer f.millDone.Done()
+
+		f.mu.Lock()
+		for f.millPending {
+			f.millPending = false
+			now := f.now()
+			f.mu.Unlock()
+
+			err := f.mill(now)
+
+			f.mu.Lock()
+			if err != nil {
+				f.millErr = err
+			}
+		}
+		f.milling = false
+		f.mu.Unlock()
+	}()
+}
+
+// mill deletes the backups beyond the maximum count, as well as those older than the
+// maximum age, then compresses the remaining ones.
+func (f *rotatingFile) mill(now time.Time) error {
+	if f.maxBackups == 0 && f.maxAge == 0 && f.compress == "" {
+		return nil
+	}
+
+	backups, err := f.backups()
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(f.path)
+	var errs []error
+	cutoff := now.Add(-f.maxAge)
+	var kept []backupFile
+	for i, b := range backups {
+		if (f.maxBackups > 0 && i >= f.maxBackups) || (f.maxAge > 0 && b.time.Before(cutoff)) {
+			if err := os.Remove(filepath.Join(dir, b.name)); err != nil && !os.IsNotExist(err) {
+				errs = append(errs, fmt.Errorf("failed to delete log file backup: %v", err))
+			}
+			continue
+		}
+		kept = append(kept, b)
+	}
+
+	if f.compress != "" {
+		for _, b := range kept {
+			if b.compressed {
+				continue
+			}
+			if err := compressFile(filepath.Join(dir, b.name), f.compress); err != nil {
+				errs = append(errs, fmt.Errorf("failed to compress log file backup: %v", err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Sync commits the file to stable storage.
+func (f *rotatingFile) Sync() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	return f.file.Sync()
+}
+
+// Close closes the file and waits for backups to be processed. The file is reopened if
+// written to again.
+func (f *rotatingFile) Close() error {
+	f.mu.Lock()
+	var err error
+	if f.file != nil {
+		err = f.file.Close()
+		f.file = nil
+	}
+	f.mu.Unlock()
+
+	f.millDone.Wait()
+	return err
+}
+
+// compressFile compresses the file at path into a new file with the extension of the
+// compression method, and deletes the original.
+func compressFile(path, method string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	dstPath := path + compressionExtensions[method]
+	dst, err := os.OpenFile(dstPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	if err := writeCompressed(dst, src, method); err != nil {
+		_ = dst.Close()
+		_ = os.Remove(dstPath)
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		_ = os.Remove(dstPath)
+		return err
+	}
+
+	return os.Remove(path)
+}
+
+func writeCompressed(dst *os.File, src io.Reader, method string) error {
+	var w io.WriteCloser
+	if method == CompressZstd {
+		zw, err := zstd.NewWriter(dst)
+		if err != nil {
+			return err
+		}
+		w = zw
+	} else {
+		w = gzip.NewWriter(dst)
+	}
+
+	if _, err := io.Copy(w, src); err != nil {
+		_ = w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return dst.Sync()
+}
@@ -0,0 +1,465 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/spf13/pflag"
+)
+
+func newTestRotatingFile(t *testing.T, o *Options, now *time.Time) (*rotatingFile, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	ws, err := newRotatingSink(filepath.Join(dir, "app.log"), o)
+	if err != nil {
+		t.Fatalf("Got error '%v', expected success", err)
+	}
+	f := ws.(*rotatingFile)
+	f.now = func() time.Time { return *now }
+	t.Cleanup(func() { _ = f.Close() })
+	return f, dir
+}
+
+// write writes s to the file, and waits for backups to be processed.
+func write(t *testing.T, f *rotatingFile, s string) {
+	t.Helper()
+
+	if _, err := f.Write([]byte(s)); err != nil {
+		t.Fatalf("Got error '%v', expected success", err)
+	}
+	f.millDone.Wait()
+}
+
+// readDir returns the content of each file in dir, keyed by name. Compressed files are
+// decompressed.
+func readDir(t *testing.T, dir string) map[string]string {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Got error '%v', expected success", err)
+	}
+	files := make(map[string]string)
+	for _, e := range entries {
+		content, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			t.Fatalf("Got error '%v', expected success", err)
+		}
+
+		var r io.Reader
+		switch filepath.Ext(e.Name()) {
+		case compressionExtensions[CompressGzip]:
+			if r, err = gzip.NewReader(bytes.NewReader(content)); err != nil {
+				t.Fatalf("Got error '%v' reading %s, expected success", err, e.Name())
+			}
+		case compressionExtensions[CompressZstd]:
+			if r, err = zstd.NewReader(bytes.NewReader(content)); err != nil {
+				t.Fatalf("Got error '%v' reading %s, expected success", err, e.Name())
+			}
+		}
+		if r != nil {
+			if content, err = io.ReadAll(r); err != nil {
+				t.Fatalf("Got error '%v' reading %s, expected success", err, e.Name())
+			}
+		}
+		files[e.Name()] = string(content)
+	}
+	return files
+}
+
+func TestRotatingFileDaily(t *testing.T) {
+	o := testOptions()
+	o.RotationInterval = RotateDaily
+	o.RotationOffset = "2h"
+	o.RotationTimeZone = "UTC"
+	o.RotationBackupFormat = "{name}-2006-01-02{ext}"
+	o.RotationMaxBackups = 2
+
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	f, dir := newTestRotatingFile(t, o, &now)
+
+	write(t, f, "a\n")
+	now = time.Date(2024, 5, 2, 1, 59, 0, 0, time.UTC)
+	write(t, f, "b\n")
+	now = time.Date(2024, 5, 2, 2, 0, 0, 0, time.UTC)
+	write(t, f, "c\n")
+
+	expected := map[string]string{
+		"app-2024-05-01.log": "a\nb\n",
+		"app.log":            "c\n",
+	}
+	if got := readDir(t, dir); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Got %v, expected %v", got, expected)
+	}
+
+	now = time.Date(2024, 5, 4, 12, 0, 0, 0, time.UTC)
+	write(t, f, "d\n")
+	now = time.Date(2024, 5, 5, 3, 0, 0, 0, time.UTC)
+	write(t, f, "e\n")
+
+	// the oldest backup is deleted, leaving two
+	expected = map[string]string{
+		"app-2024-05-02.log": "c\n",
+		"app-2024-05-04.log": "d\n",
+		"app.log":            "e\n",
+	}
+	if got := readDir(t, dir); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Got %v, expected %v", got, expected)
+	}
+}
+
+func TestRotatingFileSize(t *testing.T) {
+	o := testOptions()
+	o.RotationTimeZone = "UTC"
+	o.RotationMaxBackups = 1
+	o.RotationMaxSizeBytes = 10 * Byte
+
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	f, dir := newTestRotatingFile(t, o, &now)
+
+	write(t, f, "first\n")
+	write(t, f, "second\n")
+
+	expected := map[string]string{
+		"app-2024-05-01T10-00-00.000.log": "first\n",
+		"app.log":                         "second\n",
+	}
+	if got := readDir(t, dir); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Got %v, expected %v", got, expected)
+	}
+
+	// rotating again at the same time numbers the backup, and the older one is deleted
+	write(t, f, "third\n")
+
+	expected = map[string]string{
+		"app-2024-05-01T10-00-00.000.1.log": "second\n",
+		"app.log":                           "third\n",
+	}
+	if got := readDir(t, dir); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Got %v, expected %v", got, expected)
+	}
+}
+
+func TestConfigureRotationSize(t *testing.T) {
+	dir := t.TempDir()
+	o := testOptions()
+	o.RotateOutputPath = filepath.Join(dir, "app.log")
+	o.RotationMaxAge = 0
+	o.RotationMaxBackups = 0
+
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	o.AddFlags(fs)
+	if err := fs.Parse([]string{"--log_rotate_max_size", "1Ki"}); err != nil {
+		t.Fatalf("Got error '%v', expected success", err)
+	}
+
+	_, err := captureStdout(func() {
+		if err := Configure(o); err != nil {
+			t.Errorf("Got err '%v', expecting success", err)
+		}
+		for i := 0; i < 100; i++ {
+			Infof("message %d", i)
+		}
+		_ = Sync()
+	})
+	_ = Configure(testOptions())
+	if err != nil {
+		t.Fatalf("Got error '%v', expected success", err)
+	}
+
+	files := readDir(t, dir)
+	if len(files) < 5 {
+		t.Errorf("Got %d files, expected the log file to be rotated several times", len(files))
+	}
+	lines := 0
+	for name, content := range files {
+		if len(content) > 1024 {
+			t.Errorf("Got %d bytes in %s, expected at most 1Ki", len(content), name)
+		}
+		lines += strings.Count(content, "\n")
+	}
+	if lines != 100 {
+		t.Errorf("Got %d lines, expected every message to be written once", lines)
+	}
+}
+
+func TestRotatingFileMaxAge(t *testing.T) {
+	o := testOptions()
+	o.RotationInterval = RotateHourly
+	o.RotationTimeZone = "UTC"
+	o.RotationMaxAge = 1
+
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	f, dir := newTestRotatingFile(t, o, &now)
+
+	for _, step := range []time.Duration{0, time.Hour, 24 * time.Hour, time.Hour} {
+		now = now.Add(step)
+		write(t, f, now.Format(time.Kitchen)+"\n")
+	}
+
+	var names []string
+	for name := range readDir(t, dir) {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// the backups of 10:00 and 11:00 on the first day are more than a day old
+	expected := []string{"app-2024-05-02T11-00-00.000.log", "app.log"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Got %v, expected %v", names, expected)
+	}
+}
+
+func TestRotatingFileExisting(t *testing.T) {
+	o := testOptions()
+	o.RotationInterval = RotateDaily
+	o.RotationTimeZone = "UTC"
+	o.RotationBackupFormat = "{name}{ext}.2006-01-02"
+
+	now := time.Date(2024, 5, 3, 10, 0, 0, 0, time.UTC)
+	f, dir := newTestRotatingFile(t, o, &now)
+
+	// a file left over from a previous run two days ago is rotated before writing
+	_ = os.WriteFile(f.path, []byte("old\n"), 0o644)
+	_ = os.Chtimes(f.path, now, now.Add(-48*time.Hour))
+
+	write(t, f, "new\n")
+
+	expected := map[string]string{
+		"app.log.2024-05-01": "old\n",
+		"app.log":            "new\n",
+	}
+	if got := readDir(t, dir); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Got %v, expected %v", got, expected)
+	}
+}
+
+func TestRotatingFileCompress(t *testing.T) {
+	for _, method := range []string{CompressGzip, CompressZstd} {
+		t.Run(method, func(t *testing.T) {
+			o := testOptions()
+			o.RotationInterval = RotateDaily
+			o.RotationTimeZone = "UTC"
+			o.RotationBackupFormat = "{name}-2006-01-02{ext}"
+			o.RotationMaxBackups = 2
+			o.RotationCompress = method
+			ext := compressionExtensions[method]
+
+			now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+			f, dir := newTestRotatingFile(t, o, &now)
+
+			// a backup whose compression was interrupted is compressed again
+			interrupted := filepath.Join(dir, "app-2024-04-30.log")
+			_ = os.WriteFile(interrupted, []byte("interrupted\n"), 0o644)
+			_ = os.WriteFile(interrupted+ext, []byte("partial"), 0o644)
+
+			for day := 1; day <= 4; day++ {
+				now = time.Date(2024, 5, day, 10, 0, 0, 0, time.UTC)
+				write(t, f, fmt.Sprintf("day %d\n", day))
+			}
+
+			// the compressed backups count towards the maximum
+			expected := map[string]string{
+				"app-2024-05-02.log" + ext: "day 2\n",
+				"app-2024-05-03.log" + ext: "day 3\n",
+				"app.log":                  "day 4\n",
+			}
+			if got := readDir(t, dir); !reflect.DeepEqual(got, expected) {
+				t.Errorf("Got %v, expected %v", got, expected)
+			}
+		})
+	}
+}
+
+func TestRotationBoundaries(t *testing.T) {
+	kolkata, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Skipf("Time zone database unavailable: %v", err)
+	}
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("Time zone database unavailable: %v", err)
+	}
+
+	cases := []struct {
+		name  string
+		r     rotation
+		t     time.Time
+		start time.Time
+		next  time.Time
+	}{
+		{
+			name:  "hourly with offset in a half-hour time zone",
+			r:     rotation{interval: RotateHourly, offset: 15 * time.Minute, location: kolkata},
+			t:     time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+			start: time.Date(2024, 5, 1, 15, 15, 0, 0, kolkata),
+			next:  time.Date(2024, 5, 1, 16, 15, 0, 0, kolkata),
+		},
+		{
+			name:  "daily before the offset",
+			r:     rotation{interval: RotateDaily, offset: 2 * time.Hour, location: time.UTC},
+			t:     time.Date(2024, 5, 1, 1, 0, 0, 0, time.UTC),
+			start: time.Date(2024, 4, 30, 2, 0, 0, 0, time.UTC),
+			next:  time.Date(2024, 5, 1, 2, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "daily across a daylight saving time change",
+			r:     rotation{interval: RotateDaily, location: newYork},
+			t:     time.Date(2024, 3, 10, 12, 0, 0, 0, newYork),
+			start: time.Date(2024, 3, 10, 0, 0, 0, 0, newYork),
+			next:  time.Date(2024, 3, 11, 0, 0, 0, 0, newYork),
+		},
+		{
+			name: "no interval",
+			r:    rotation{location: time.UTC},
+			t:    time.Date(2024, 5, 1, 1, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			start := c.r.periodStart(c.t)
+			if !start.Equal(c.start) {
+				t.Errorf("Got start %v, expected %v", start, c.start)
+			}
+			if next := c.r.nextBoundary(start); !next.Equal(c.next) {
+				t.Errorf("Got next boundary %v, expected %v", next, c.next)
+			}
+		})
+	}
+}
+
+func TestBackupFormat(t *testing.T) {
+	// a time that every layout represents exactly
+	ts := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		format string
+		name   string
+	}{
+		{DefaultRotationBackupFormat, "app-2024-01-01T00-00-00.000.log"},
+		{"{name}{ext}.20060102", "app.log.20240101"},
+		{"backup-{name}-2006-01-02-15{ext}", ""},
+		{"{ext}{name}_2006", ".logapp_2024"},
+	}
+	for _, c := range cases {
+		f, err := parseBackupFormat(c.format)
+		if c.name == "" {
+			if err == nil || !strings.Contains(err.Error(), "placeholder must come before or after the time layout") {
+				t.Errorf("Got %v for %s, expecting a placeholder error", err, c.format)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Got error '%v' for %s, expected success", err, c.format)
+		}
+
+		if got := f.name("app.log", ts, 0); got != c.name {
+			t.Errorf("Got %s for %s, expected %s", got, c.format, c.name)
+		}
+		for n := 0; n < 2; n++ {
+			name := f.name("app.log", ts, n)
+			pt, pn, ok := f.parse("app.log", name, time.UTC)
+			if !ok || pn != n || !pt.Equal(ts) {
+				t.Errorf("Got %v, %d, %v parsing %s, expected %v, %d", pt, pn, ok, name, ts, n)
+			}
+		}
+
+		for _, other := range []string{"app.log", "other.log", "app-notatime.log"} {
+			if _, _, ok := f.parse("app.log", other, time.UTC); ok {
+				t.Errorf("Got %s parsed as a backup for %s, expected it to be ignored", other, c.format)
+			}
+		}
+	}
+
+	if _, err := parseBackupFormat("{name}-backup{ext}"); err == nil || err.Error() != "the time layout is missing" {
+		t.Errorf("Got %v, expecting a missing layout error", err)
+	}
+}
+
+// openFiles returns the number of file descriptors of the process open on path, or -1
+// if they can't be listed.
+func openFiles(path string) int {
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return -1
+	}
+
+	n := 0
+	for _, e := range entries {
+		if target, err := os.Readlink(filepath.Join("/proc/self/fd", e.Name())); err == nil && target == path {
+			n++
+		}
+	}
+	return n
+}
+
+func TestConfigureRotationClose(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	sinkPath := filepath.Join(dir, "sink.log")
+	if openFiles(path) < 0 {
+		t.Skip("open files can't be listed")
+	}
+
+	o := testOptions()
+	o.OutputPath = ""
+	o.RotateOutputPath = path
+	o.RotationMaxSizeBytes = 1
+	o.RotationMaxAge = 0
+	o.RotationMaxBackups = 0
+	o.RotationCompress = CompressGzip
+	o.Sinks = []SinkOptions{{Path: sinkPath, Rotate: true}}
+
+	for i := 0; i < 3; i++ {
+		if err := Configure(o); err != nil {
+			t.Fatalf("Got err '%v', expecting success", err)
+		}
+		// every entry rotates the files, and compresses the backup in the background
+		Infof("first %d", i)
+		Infof("second %d", i)
+		if n := openFiles(path); n != 1 {
+			t.Errorf("Got %d open files, expected only the current file to be open", n)
+		}
+	}
+
+	_ = Configure(testOptions())
+	for _, p := range []string{path, sinkPath} {
+		if n := openFiles(p); n != 0 {
+			t.Errorf("Got %d open files on %s, expected it to be closed", n, p)
+		}
+	}
+
+	// the backups were compressed before configuring returned
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		if name := e.Name(); name != "app.log" && name != "sink.log" && !strings.HasSuffix(name, ".gz") {
+			t.Errorf("Got backup %s, expected backups to be compressed", name)
+		}
+	}
+}
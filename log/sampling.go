@@ -0,0 +1,270 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"fmt"
+	"hash/fnv"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Sampling limits the number of similar entries written by a scope. Entries are similar
+// if they have the same level and message. In every interval, the first entries of each
+// kind are written, then every Thereafter-th entry, or none if Thereafter is 0. Fatal
+// entries are never sampled.
+//
+// Once an interval during which entries were suppressed is over, an entry reporting how
+// many were suppressed is written in their place.
+type Sampling struct {
+	Interval   time.Duration
+	First      int
+	Thereafter int
+}
+
+// String returns the sampling in the form <first>/<thereafter>.
+func (s *Sampling) String() string {
+	if s == nil {
+		return "none"
+	}
+	return fmt.Sprintf("%d/%d", s.First, s.Thereafter)
+}
+
+// samplerBuckets is the number of counters per level. Messages hashing to the same
+// counter are sampled together.
+const samplerBuckets = 1024
+
+// sampler samples the entries of a scope.
+type sampler struct {
+	Sampling
+	counters [zapcore.ErrorLevel - zapcore.DebugLevel + 1][samplerBuckets]sampleCounter
+}
+
+type sampleCounter struct {
+	resetAt    atomic.Int64
+	count      atomic.Uint64
+	suppressed atomic.Uint64
+	// msg is the message of the first entry suppressed in the interval.
+	msg atomic.Pointer[string]
+}
+
+// SetSampling sets the sampling of the entries written by this logger. nil disables
+// sampling.
+func (l *logger) SetSampling(s *Sampling) {
+	if s == nil {
+		l.root().sampler.Store(nil)
+		return
+	}
+	l.root().sampler.Store(&sampler{Sampling: *s})
+}
+
+// GetSampling returns the sampling of the entries written by this logger, or nil if
+// sampling is disabled.
+func (l *logger) GetSampling() *Sampling {
+	s := l.root().sampler.Load()
+	if s == nil {
+		return nil
+	}
+	sampling := s.Sampling
+	return &sampling
+}
+
+// sample returns whether the entry should be written. Suppressed entries are reported
+// through the root logger l once the interval is over.
+func (s *sampler) sample(l *logger, e zapcore.Entry) bool {
+	if e.Level < zapcore.DebugLevel || e.Level > zapcore.ErrorLevel {
+		return true
+	}
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(e.Message))
+	c := &s.counters[e.Level-zapcore.DebugLevel][h.Sum32()%samplerBuckets]
+
+	// entries may have no time, such as slog records
+	t := e.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	now := t.UnixNano()
+	n := c.inc(now, s.Interval)
+	first := uint64(s.First)
+	if n <= first || (s.Thereafter > 0 && (n-first)%uint64(s.Thereafter) == 0) {
+		return true
+	}
+
+	if c.suppressed.Add(1) == 1 {
+		msg, level := e.Message, e.Level
+		c.msg.Store(&msg)
+		time.AfterFunc(time.Duration(c.resetAt.Load()-now), func() {
+			if n := c.suppressed.Swap(0); n > 0 {
+				l.emit(zapcore.Entry{
+					Level:   level,
+					Time:    time.Now(),
+					Message: "suppressed similar log entries",
+				}, []zapcore.Field{zap.String("sampled", *c.msg.Load()), zap.Uint64("suppressed", n)})
+			}
+		})
+	}
+	return false
+}
+
+// inc counts an entry at time t, in nanoseconds, and returns the number of entries
+// counted in the current interval.
+func (c *sampleCounter) inc(t int64, interval time.Duration) uint64 {
+	resetAt := c.resetAt.Load()
+	if resetAt > t {
+		return c.count.Add(1)
+	}
+
+	c.count.Store(1)
+	if !c.resetAt.CompareAndSwap(resetAt, t+interval.Nanoseconds()) {
+		// another entry started the interval
+		return c.count.Add(1)
+	}
+	return 1
+}
+
+// scopedSampling is the parsed form of a per-scope sampling list such as
+// "100/10,engine:none".
+type scopedSampling struct {
+	// all is the sampling applied to scopes not listed in scopes.
+	all *Sampling
+	// scopes maps scope names to their explicitly requested sampling.
+	scopes map[string]*Sampling
+}
+
+// parseScopedSampling parses a comma-separated list of samplings in the form
+// <scope>:<first>/<thereafter>, where none disables sampling. Entries without a scope
+// apply to all scopes not listed explicitly. Listed scopes don't need to be registered yet.
+func parseScopedSampling(sampling string, interval time.Duration) (*scopedSampling, error) {
+	ss := &scopedSampling{
+		scopes: make(map[string]*Sampling),
+	}
+
+	for _, entry := range strings.Split(sampling, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		scope, value, scoped := strings.Cut(entry, ":")
+		if !scoped {
+			scope, value = "", entry
+		}
+
+		var s *Sampling
+		if value != "none" {
+			first, thereafter, ok := strings.Cut(value, "/")
+			f, ferr := strconv.Atoi(first)
+			t, terr := strconv.Atoi(thereafter)
+			if !ok || ferr != nil || terr != nil || f < 0 || t < 0 {
+				return nil, fmt.Errorf("invalid sampling '%s', must be <first>/<thereafter> or none", entry)
+			}
+			s = &Sampling{Interval: interval, First: f, Thereafter: t}
+		}
+
+		if !scoped {
+			ss.all = s
+			continue
+		}
+
+		if err := checkScopeName(scope); err != nil {
+			return nil, err
+		}
+		ss.scopes[scope] = s
+	}
+
+	return ss, nil
+}
+
+// samplingFor returns the sampling requested for the named scope.
+func (ss *scopedSampling) samplingFor(scope string) *Sampling {
+	if s, ok := ss.scopes[scope]; ok {
+		return s
+	}
+	return ss.all
+}
+
+// callsite identifies the call to Every at a line of code for a scope.
+type callsite struct {
+	pc     uintptr
+	logger *logger
+}
+
+// callsiteLimit tracks the entries of a callsite.
+type callsiteLimit struct {
+	last       atomic.Int64
+	suppressed atomic.Uint64
+}
+
+var callsiteLimits sync.Map // callsite -> *callsiteLimit
+
+// discardScope is returned by Every for calls that are rate limited.
+var discardScope = func() *Scope {
+	s := &Scope{logger: &logger{name: "discard"}}
+	s.SetOutputLevel(NoneLevel)
+	s.SetStackTraceLevel(NoneLevel)
+	s.SetLogCallers(false)
+	return s
+}()
+
+// Every rate limits the entries logged at the line of code calling it to one per
+// interval d, as in:
+//
+//	log.Every(time.Minute).Warnf("queue is full, dropping %s", item)
+//
+// It returns a scope writing through the default scope at most once per interval, and
+// a scope discarding everything otherwise. The number of calls discarded since the
+// last entry written is attached to the next one as the suppressed field.
+func Every(d time.Duration) *Scope {
+	return every(contextDefaultScope, d)
+}
+
+// Every rate limits the entries logged by this scope at the line of code calling it to
+// one per interval d, like the package-level Every.
+func (s *Scope) Every(d time.Duration) *Scope {
+	return every(s, d)
+}
+
+func every(s *Scope, d time.Duration) *Scope {
+	var pcs [1]uintptr
+	runtime.Callers(3, pcs[:])
+
+	key := callsite{pc: pcs[0], logger: s.root()}
+	v, ok := callsiteLimits.Load(key)
+	if !ok {
+		v, _ = callsiteLimits.LoadOrStore(key, &callsiteLimit{})
+	}
+	limit := v.(*callsiteLimit)
+
+	now := time.Now().UnixNano()
+	last := limit.last.Load()
+	if (last != 0 && now-last < d.Nanoseconds()) || !limit.last.CompareAndSwap(last, now) {
+		limit.suppressed.Add(1)
+		return discardScope
+	}
+
+	if n := limit.suppressed.Swap(0); n > 0 {
+		return s.With("suppressed", n)
+	}
+	return s
+}
@@ -0,0 +1,225 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestSampling(t *testing.T) {
+	lines, err := captureStdout(func() {
+		o := testOptions()
+		o.Sampling = "2/3"
+		o.SamplingInterval = "50ms"
+		if err := Configure(o); err != nil {
+			t.Errorf("Got err '%v', expecting success", err)
+		}
+
+		for i := 0; i < 10; i++ {
+			Info("hot")
+		}
+		Info("cold")
+
+		// the suppressed entries are reported once the interval is over
+		time.Sleep(200 * time.Millisecond)
+		_ = Sync()
+
+		_ = Configure(testOptions())
+	})
+	if err != nil {
+		t.Fatalf("Got error '%v', expected success", err)
+	}
+
+	patterns := []string{
+		`\tinfo\thot`,
+		`\tinfo\thot`,
+		`\tinfo\thot`,
+		`\tinfo\thot`,
+		`\tinfo\tcold`,
+		`\tinfo\tsuppressed similar log entries\t{"sampled": "hot", "suppressed": 6}`,
+		"",
+	}
+	if len(lines) != len(patterns) {
+		t.Fatalf("Got %d lines of output %v, expecting %d", len(lines), lines, len(patterns))
+	}
+	for i, pat := range patterns {
+		if match, _ := regexp.MatchString(pat+"$", lines[i]); !match {
+			t.Errorf("Got '%s', expecting to match '%s'", lines[i], pat)
+		}
+	}
+}
+
+func TestSamplingZeroTime(t *testing.T) {
+	s := RegisterScope("sampledslog", "")
+
+	lines, err := captureStdout(func() {
+		o := testOptions()
+		o.Sampling = "sampledslog:1/0"
+		o.SamplingInterval = "50ms"
+		if err := Configure(o); err != nil {
+			t.Errorf("Got err '%v', expecting success", err)
+		}
+
+		// slog records may have a zero time
+		h := NewSlogHandler(s)
+		send := func() {
+			_ = h.Handle(context.Background(), slog.NewRecord(time.Time{}, slog.LevelInfo, "hot", 0))
+		}
+		send()
+		send()
+		time.Sleep(200 * time.Millisecond)
+		send()
+		time.Sleep(200 * time.Millisecond)
+		_ = Sync()
+
+		_ = Configure(testOptions())
+	})
+	if err != nil {
+		t.Fatalf("Got error '%v', expected success", err)
+	}
+
+	// the interval resets, and the suppressed entry is reported
+	patterns := []string{
+		`^info\tsampledslog\thot`,
+		`\tinfo\tsampledslog\tsuppressed similar log entries\t{"sampled": "hot", "suppressed": 1}`,
+		`^info\tsampledslog\thot`,
+		"",
+	}
+	if len(lines) != len(patterns) {
+		t.Fatalf("Got %d lines of output %v, expecting %d", len(lines), lines, len(patterns))
+	}
+	for i, pat := range patterns {
+		if match, _ := regexp.MatchString(pat+"$", lines[i]); !match {
+			t.Errorf("Got '%s', expecting to match '%s'", lines[i], pat)
+		}
+	}
+}
+
+func TestScopedSampling(t *testing.T) {
+	s := RegisterScope("sampled", "")
+
+	o := testOptions()
+	o.Sampling = "1/0,sampled:none"
+	if err := Configure(o); err != nil {
+		t.Fatalf("Got err '%v', expecting success", err)
+	}
+	defer func() { _ = Configure(testOptions()) }()
+
+	if got := defaultScope.GetSampling().String(); got != "1/0" {
+		t.Errorf("Got sampling %s for the default scope, expecting 1/0", got)
+	}
+	if got := s.GetSampling(); got != nil {
+		t.Errorf("Got sampling %s for the sampled scope, expecting none", got)
+	}
+
+	// scopes registered later inherit the default sampling
+	if got := RegisterScope("sampled_later", "").GetSampling().String(); got != "1/0" {
+		t.Errorf("Got sampling %s for a new scope, expecting 1/0", got)
+	}
+}
+
+func TestParseScopedSampling(t *testing.T) {
+	RegisterScope("engine", "")
+
+	ss, err := parseScopedSampling("100/10, engine:5/0", time.Second)
+	if err != nil {
+		t.Fatalf("Got err '%v', expecting success", err)
+	}
+	if got := ss.samplingFor("engine"); *got != (Sampling{Interval: time.Second, First: 5}) {
+		t.Errorf("Got %+v, expecting 5/0 every second", *got)
+	}
+	if got := ss.samplingFor("other"); *got != (Sampling{Interval: time.Second, First: 100, Thereafter: 10}) {
+		t.Errorf("Got %+v, expecting 100/10 every second", *got)
+	}
+
+	for _, s := range []string{"100", "a/1", "1/-1", "no.scope:1/1", ":1/1"} {
+		if _, err := parseScopedSampling(s, time.Second); err == nil {
+			t.Errorf("Got success parsing '%s', expecting an error", s)
+		}
+	}
+
+	ss, err = parseScopedSampling("notyetscope:5/0", time.Second)
+	if err != nil {
+		t.Fatalf("Got err '%v', expecting success for an unregistered scope", err)
+	}
+	if got := ss.samplingFor("notyetscope"); *got != (Sampling{Interval: time.Second, First: 5}) {
+		t.Errorf("Got %+v, expecting 5/0 every second", *got)
+	}
+}
+
+func TestLateScopeSampling(t *testing.T) {
+	o := DefaultOptions()
+	o.Sampling = "1/0,latesampled:5/2"
+	if err := Configure(o); err != nil {
+		t.Fatalf("Unable to configure logging: %v", err)
+	}
+	defer func() { _ = Configure(DefaultOptions()) }()
+
+	s := RegisterScope("latesampled", "")
+	if got := s.GetSampling(); got == nil || got.First != 5 || got.Thereafter != 2 {
+		t.Errorf("Got %+v, expecting the configured 5/2 sampling", got)
+	}
+	other := RegisterScope("latesampledother", "")
+	if got := other.GetSampling(); got == nil || got.First != 1 || got.Thereafter != 0 {
+		t.Errorf("Got %+v, expecting the catch-all 1/0 sampling", got)
+	}
+}
+
+func TestEvery(t *testing.T) {
+	s := RegisterScope("every", "")
+	callsiteLimits.Range(func(key, _ any) bool {
+		callsiteLimits.Delete(key)
+		return true
+	})
+
+	lines, err := captureStdout(func() {
+		if err := Configure(testOptions()); err != nil {
+			t.Errorf("Got err '%v', expecting success", err)
+		}
+
+		for i := 0; i < 5; i++ {
+			Every(time.Hour).Info("package")
+		}
+		for i := 0; i < 4; i++ {
+			s.Every(50*time.Millisecond).Infof("scope %d", i)
+			if i == 2 {
+				time.Sleep(100 * time.Millisecond)
+			}
+		}
+		_ = Sync()
+	})
+	if err != nil {
+		t.Fatalf("Got error '%v', expected success", err)
+	}
+
+	patterns := []string{
+		`\tinfo\tpackage`,
+		`\tinfo\tevery\tscope 0`,
+		`\tinfo\tevery\tscope 3\t{"suppressed": 2}`,
+		"",
+	}
+	if len(lines) != len(patterns) {
+		t.Fatalf("Got %d lines of output %v, expecting %d", len(lines), lines, len(patterns))
+	}
+	for i, pat := range patterns {
+		if match, _ := regexp.MatchString(pat+"$", lines[i]); !match {
+			t.Errorf("Got '%s', expecting to match '%s'", lines[i], pat)
+		}
+	}
+}
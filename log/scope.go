@@ -0,0 +1,151 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Scope is a named logger whose output level, stack trace level and caller
+// logging can be controlled independently of all other scopes.
+//
+// Scopes are registered once, typically as a package-level variable, and then
+// used in place of the package-level logging functions:
+//
+//	var engineLog = log.RegisterScope("engine", "Messages from the apply engine")
+//
+//	engineLog.Infof("applying %d resources", n)
+type Scope struct {
+	*logger
+	description string
+}
+
+var (
+	scopes    = make(map[string]*Scope)
+	scopeLock sync.RWMutex
+
+	// scopeNameWidth is the length of the longest name of the registered scopes other
+	// than the default one, so that pretty output can align them.
+	scopeNameWidth atomic.Int64
+
+	// configuredScopes are the per-scope settings last configured, applied to the
+	// scopes registered afterwards.
+	configuredScopes atomic.Pointer[scopeSettings]
+)
+
+// scopeSettings are the parsed output and stack trace levels and sampling of the
+// options. The stack trace levels are nil if not configured.
+type scopeSettings struct {
+	output, stackTrace *scopedLevels
+	sampling           *scopedSampling
+}
+
+// RegisterScope registers a new logging scope. If the same name is used multiple
+// times, a single Scope instance is returned.
+//
+// Newly registered scopes start with the levels and sampling last configured for
+// them, and with the caller setting of the default scope. Scope names cannot include
+// colons, commas, periods or spaces.
+func RegisterScope(name string, description string) *Scope {
+	return registerScope(name, description, 0)
+}
+
+func registerScope(name string, description string, callerSkip int) *Scope {
+	if strings.ContainsAny(name, ":,. ") {
+		panic(fmt.Sprintf("scope name %q is invalid, it cannot contain colons, commas, periods, or spaces", name))
+	}
+
+	scopeLock.Lock()
+	defer scopeLock.Unlock()
+
+	s, ok := scopes[name]
+	if !ok {
+		s = &Scope{
+			logger: &logger{
+				name:       name,
+				callerSkip: callerSkip,
+			},
+			description: description,
+		}
+		if d, ok := scopes[DefaultLoggerName]; ok {
+			s.SetOutputLevel(d.GetOutputLevel())
+			s.SetStackTraceLevel(d.GetStackTraceLevel())
+			s.SetLogCallers(d.GetLogCallers())
+			s.SetSampling(d.GetSampling())
+		} else {
+			s.SetOutputLevel(DefaultOutputLevel)
+			s.SetStackTraceLevel(DefaultStackTraceLevel)
+			s.SetLogCallers(false)
+		}
+		if cs := configuredScopes.Load(); cs != nil {
+			s.SetOutputLevel(cs.output.levelFor(name))
+			if cs.stackTrace != nil {
+				s.SetStackTraceLevel(cs.stackTrace.levelFor(name))
+			}
+			s.SetSampling(cs.sampling.samplingFor(name))
+		}
+		scopes[name] = s
+
+		if name != DefaultLoggerName && int64(len(name)) > scopeNameWidth.Load() {
+			scopeNameWidth.Store(int64(len(name)))
+		}
+	}
+
+	return s
+}
+
+// FindScope returns a previously registered scope, or nil if the named scope wasn't previously registered.
+func FindScope(name string) *Scope {
+	scopeLock.RLock()
+	defer scopeLock.RUnlock()
+
+	return scopes[name]
+}
+
+// Scopes returns a snapshot of the currently defined set of scopes, keyed by name.
+func Scopes() map[string]*Scope {
+	scopeLock.RLock()
+	defer scopeLock.RUnlock()
+
+	s := make(map[string]*Scope, len(scopes))
+	for k, v := range scopes {
+		s[k] = v
+	}
+
+	return s
+}
+
+// Name returns this scope's name.
+func (s *Scope) Name() string {
+	return s.name
+}
+
+// Description returns this scope's description.
+func (s *Scope) Description() string {
+	return s.description
+}
+
+// With returns a child of this scope that attaches the given key/value pairs to every
+// entry it writes. The child shares the levels and caller setting of this scope, so
+// changing them on either affects both. The child isn't registered as a scope.
+func (s *Scope) With(keysAndValues ...any) *Scope {
+	return &Scope{
+		logger:      s.logger.with(keysAndValues),
+		description: s.description,
+	}
+}
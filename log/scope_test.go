@@ -0,0 +1,230 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRegisterScope(t *testing.T) {
+	s := RegisterScope("testscope", "a test scope")
+	if s.Name() != "testscope" {
+		t.Errorf("Got name %q, expecting testscope", s.Name())
+	}
+	if s.Description() != "a test scope" {
+		t.Errorf("Got description %q, expecting 'a test scope'", s.Description())
+	}
+
+	if s2 := RegisterScope("testscope", "another description"); s2 != s {
+		t.Error("Expecting the same scope instance when registering a name twice")
+	}
+
+	if FindScope("testscope") != s {
+		t.Error("Expecting FindScope to return the registered scope")
+	}
+
+	if FindScope("nonexistent") != nil {
+		t.Error("Expecting FindScope to return nil for an unknown scope")
+	}
+
+	all := Scopes()
+	if all["testscope"] != s || all[DefaultLoggerName] != defaultScope {
+		t.Errorf("Got scopes %v, expecting testscope and default", all)
+	}
+}
+
+func TestRegisterInvalidScope(t *testing.T) {
+	for _, name := range []string{"a:b", "a,b", "a.b", "a b"} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expecting a panic for scope name %q", name)
+				}
+			}()
+			RegisterScope(name, "")
+		})
+	}
+}
+
+func TestScopeLevels(t *testing.T) {
+	s := RegisterScope("levels", "")
+
+	lines, err := captureStdout(func() {
+		o := testOptions()
+		if err := Configure(o); err != nil {
+			t.Errorf("Got err '%v', expecting success", err)
+		}
+
+		s.SetOutputLevel(DebugLevel)
+		s.Debug("scope-debug")
+		Debug("default-debug")
+		s.SetOutputLevel(WarnLevel)
+		s.Info("scope-info")
+		Info("default-info")
+		_ = Sync()
+	})
+	if err != nil {
+		t.Fatalf("Got error '%v', expected success", err)
+	}
+
+	patterns := []string{
+		timePattern + "\tdebug\tlevels\tscope-debug",
+		timePattern + "\tinfo\tdefault-info",
+		"",
+	}
+	if len(lines) != len(patterns) {
+		t.Fatalf("Got %d lines of output %v, expecting %d", len(lines), lines, len(patterns))
+	}
+	for i, pat := range patterns {
+		if match, _ := regexp.MatchString(pat, lines[i]); !match {
+			t.Errorf("Got '%s', expecting to match '%s'", lines[i], pat)
+		}
+	}
+}
+
+func TestScopeCaller(t *testing.T) {
+	s := RegisterScope("caller", "")
+
+	var line int
+	lines, _ := captureStdout(func() {
+		o := testOptions()
+		o.LogCaller = true
+		_ = Configure(o)
+
+		_, _, line, _ = runtime.Caller(0)
+		s.Info("scope-caller")
+		Info("default-caller")
+		_ = Sync()
+	})
+
+	patterns := []string{
+		"\tcaller\tlog/scope_test.go:" + strconv.Itoa(line+1) + "\tscope-caller",
+		"\tinfo\tlog/scope_test.go:" + strconv.Itoa(line+2) + "\tdefault-caller",
+	}
+	for i, pat := range patterns {
+		if match, _ := regexp.MatchString(pat, lines[i]); !match {
+			t.Errorf("Got '%s', expecting to match '%s'", lines[i], pat)
+		}
+	}
+}
+
+func TestScopedLevels(t *testing.T) {
+	engine := RegisterScope("engine", "")
+	backend := RegisterScope("backend", "")
+
+	o := testOptions()
+	o.OutputLevel = "warn,default:info,engine:debug"
+	o.StackTraceLevel = "backend:error"
+	if err := Configure(o); err != nil {
+		t.Fatalf("Got err '%v', expecting success", err)
+	}
+
+	cases := []struct {
+		scope           *Scope
+		outputLevel     Level
+		stackTraceLevel Level
+	}{
+		{defaultScope, InfoLevel, NoneLevel},
+		{engine, DebugLevel, NoneLevel},
+		{backend, WarnLevel, ErrorLevel},
+	}
+	for _, c := range cases {
+		if l := c.scope.GetOutputLevel(); l != c.outputLevel {
+			t.Errorf("Got output level %v for scope %s, expecting %v", l, c.scope.Name(), c.outputLevel)
+		}
+		if l := c.scope.GetStackTraceLevel(); l != c.stackTraceLevel {
+			t.Errorf("Got stack trace level %v for scope %s, expecting %v", l, c.scope.Name(), c.stackTraceLevel)
+		}
+	}
+}
+
+func TestInvalidScopedLevels(t *testing.T) {
+	RegisterScope("engine", "")
+
+	cases := []struct {
+		outputLevel     string
+		stackTraceLevel string
+		err             string
+	}{
+		{"verbose", "", "invalid output level: invalid level 'verbose'"},
+		{"engine:verbose", "", "invalid output level: invalid level 'verbose' for scope 'engine'"},
+		{"no.scope:debug", "", "invalid output level: invalid scope 'no.scope'"},
+		{"info", "engine:verbose", "invalid stack trace level: invalid level 'verbose' for scope 'engine'"},
+		{"info", ":debug", "invalid stack trace level: invalid scope ''"},
+	}
+	for i, c := range cases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			o := testOptions()
+			o.OutputLevel = c.outputLevel
+			o.StackTraceLevel = c.stackTraceLevel
+			err := Configure(o)
+			if err == nil {
+				t.Fatal("Got success, expecting error")
+			}
+			if !strings.HasPrefix(err.Error(), c.err) {
+				t.Errorf("Got error '%v', expecting prefix '%s'", err, c.err)
+			}
+		})
+	}
+}
+
+func TestScopeWith(t *testing.T) {
+	s := RegisterScope("with", "")
+
+	lines, err := captureStdout(func() {
+		o := testOptions()
+		o.JSONEncoding = true
+		if err := Configure(o); err != nil {
+			t.Errorf("Got err '%v', expecting success", err)
+		}
+
+		child := s.With("request", "r1")
+		grandchild := child.With("resource", "deploy")
+		grandchild.InfoS("grandchild", "replicas", 3)
+		child.Info("child")
+		s.Info("parent")
+		With("request", "r2").Info("default")
+
+		child.SetOutputLevel(WarnLevel)
+		if s.GetOutputLevel() != WarnLevel {
+			t.Errorf("Got %v, expecting the parent to share the child's level", s.GetOutputLevel())
+		}
+		grandchild.Info("suppressed")
+		_ = Sync()
+	})
+	if err != nil {
+		t.Fatalf("Got error '%v', expected success", err)
+	}
+
+	patterns := []string{
+		`"scope":"with","msg":"grandchild","request":"r1","resource":"deploy","replicas":3}`,
+		`"scope":"with","msg":"child","request":"r1"}`,
+		`"scope":"with","msg":"parent"}`,
+		`"msg":"default","request":"r2"}`,
+		"",
+	}
+	if len(lines) != len(patterns) {
+		t.Fatalf("Got %d lines of output %v, expecting %d", len(lines), lines, len(patterns))
+	}
+	for i, pat := range patterns {
+		if !strings.HasSuffix(lines[i], pat) {
+			t.Errorf("Got '%s', expecting suffix '%s'", lines[i], pat)
+		}
+	}
+}
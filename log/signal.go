@@ -0,0 +1,94 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build unix
+
+package log
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// HandleLevelSignals installs a handler that changes the output level of the default scope
+// when the process receives a signal:
+//
+//   - SIGUSR1 raises the level by one step towards debug
+//   - SIGUSR2 lowers the level by one step towards none
+//   - SIGHUP, if hangup is set, restores the level the default scope was last configured with
+//
+// SIGHUP is only handled if hangup is set, as handling it keeps the process running once its
+// terminal is closed. Every change is logged regardless of the new level. The returned function
+// uninstalls the handler, restoring the default behavior of these signals. On platforms lacking
+// these signals, such as Windows, HandleLevelSignals does nothing.
+func HandleLevelSignals(hangup bool) (stop func()) {
+	sigs := []os.Signal{syscall.SIGUSR1, syscall.SIGUSR2}
+	if hangup {
+		sigs = append(sigs, syscall.SIGHUP)
+	}
+
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(ch, sigs...)
+
+	go func() {
+		for {
+			select {
+			case sig := <-ch:
+				handleLevelSignal(sig)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+}
+
+// handleLevelSignal changes the output level of the default scope as requested by the signal.
+func handleLevelSignal(sig os.Signal) {
+	old := defaultScope.GetOutputLevel()
+
+	level := old
+	switch sig {
+	case syscall.SIGUSR1:
+		if level < DebugLevel {
+			level++
+		}
+	case syscall.SIGUSR2:
+		if level > NoneLevel {
+			level--
+		}
+	case syscall.SIGHUP:
+		// the options were validated when configured
+		if sl, err := parseScopedLevels(CurrentOptions().OutputLevel, DefaultOutputLevel); err == nil {
+			level = sl.levelFor(DefaultLoggerName)
+		}
+	}
+
+	defaultScope.SetOutputLevel(level)
+	defaultLogger.output(zapcore.InfoLevel,
+		fmt.Sprintf("received %v, changed output level from %s to %s", sig, levelToString[old], levelToString[level]), nil)
+}
@@ -0,0 +1,142 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux
+
+package log
+
+import (
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestHandleLevelSignals(t *testing.T) {
+	lines, err := captureStdout(func() {
+		o := testOptions()
+		o.OutputLevel = "warn"
+		if err := Configure(o); err != nil {
+			t.Errorf("Got err '%v', expecting success", err)
+		}
+
+		stop := HandleLevelSignals(true)
+		defer stop()
+
+		steps := []struct {
+			sig   syscall.Signal
+			level Level
+		}{
+			{syscall.SIGUSR1, InfoLevel},
+			{syscall.SIGUSR1, DebugLevel},
+			{syscall.SIGUSR2, InfoLevel},
+			{syscall.SIGHUP, WarnLevel},
+			{syscall.SIGUSR2, ErrorLevel},
+		}
+		for _, s := range steps {
+			if err := syscall.Kill(syscall.Getpid(), s.sig); err != nil {
+				t.Fatalf("Got error '%v' sending %v, expected success", err, s.sig)
+			}
+			if !waitForOutputLevel(s.level) {
+				t.Errorf("Got level %v after %v, expecting %v", defaultScope.GetOutputLevel(), s.sig, s.level)
+			}
+		}
+		_ = Sync()
+	})
+	if err != nil {
+		t.Fatalf("Got error '%v', expected success", err)
+	}
+
+	want := []string{
+		"received user defined signal 1, changed output level from warn to info",
+		"received user defined signal 1, changed output level from info to debug",
+		"received user defined signal 2, changed output level from debug to info",
+		"received hangup, changed output level from info to warn",
+		"received user defined signal 2, changed output level from warn to error",
+	}
+	if len(lines) != len(want)+1 {
+		t.Fatalf("Got %d lines of output %v, expecting %d", len(lines), lines, len(want)+1)
+	}
+	for i, w := range want {
+		if !strings.HasSuffix(lines[i], "\tinfo\t"+w) {
+			t.Errorf("Got '%s', expecting suffix '%s'", lines[i], w)
+		}
+	}
+}
+
+// waitForOutputLevel waits until the default scope has the given output level.
+func waitForOutputLevel(level Level) bool {
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if defaultScope.GetOutputLevel() == level {
+			return true
+		}
+	}
+	return false
+}
+
+func TestHandleLevelSignalBounds(t *testing.T) {
+	_, _ = captureStdout(func() {
+		_ = Configure(testOptions())
+		defer func() { _ = Configure(testOptions()) }()
+
+		defaultScope.SetOutputLevel(DebugLevel)
+		handleLevelSignal(syscall.SIGUSR1)
+		if l := defaultScope.GetOutputLevel(); l != DebugLevel {
+			t.Errorf("Got %v, expecting SIGUSR1 to keep debug level", l)
+		}
+
+		defaultScope.SetOutputLevel(NoneLevel)
+		handleLevelSignal(syscall.SIGUSR2)
+		if l := defaultScope.GetOutputLevel(); l != NoneLevel {
+			t.Errorf("Got %v, expecting SIGUSR2 to keep none level", l)
+		}
+
+		// SIGHUP restores the level of the last configuration
+		o := testOptions()
+		o.OutputLevel = "error"
+		_ = Configure(o)
+		defaultScope.SetOutputLevel(DebugLevel)
+		handleLevelSignal(syscall.SIGHUP)
+		if l := defaultScope.GetOutputLevel(); l != ErrorLevel {
+			t.Errorf("Got %v, expecting SIGHUP to restore the error level", l)
+		}
+	})
+}
+
+func TestHandleLevelSignalsNoHangup(t *testing.T) {
+	_, _ = captureStdout(func() {
+		_ = Configure(testOptions())
+		defer func() { _ = Configure(testOptions()) }()
+
+		stop := HandleLevelSignals(false)
+		defer stop()
+
+		// catch SIGHUP here so that it doesn't terminate the test
+		ch := make(chan os.Signal, 1)
+		signal.Notify(ch, syscall.SIGHUP)
+		defer signal.Stop(ch)
+
+		defaultScope.SetOutputLevel(DebugLevel)
+		if err := syscall.Kill(syscall.Getpid(), syscall.SIGHUP); err != nil {
+			t.Fatalf("Got error '%v' sending SIGHUP, expected success", err)
+		}
+		<-ch
+		time.Sleep(10 * time.Millisecond)
+		if l := defaultScope.GetOutputLevel(); l != DebugLevel {
+			t.Errorf("Got %v, expecting SIGHUP to be left alone", l)
+		}
+	})
+}
@@ -0,0 +1,335 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// ConsoleEncoding formats log entries in a plain console-friendly format.
+	ConsoleEncoding = "console"
+	// JSONEncoding formats log entries as JSON.
+	JSONEncoding = "json"
+	// PrettyEncoding formats log entries for people reading them in a terminal, with
+	// colored levels, aligned scopes and fields on their own lines.
+	PrettyEncoding = "pretty"
+	// LogfmtEncoding formats log entries as logfmt key=value pairs.
+	LogfmtEncoding = "logfmt"
+	// ECSEncoding formats log entries as JSON following the Elastic Common Schema.
+	ECSEncoding = "ecs"
+	// OTelEncoding formats log entries as JSON following the OpenTelemetry log data model.
+	OTelEncoding = "otel"
+)
+
+var encodingListString = []string{ConsoleEncoding, JSONEncoding, PrettyEncoding, LogfmtEncoding, ECSEncoding, OTelEncoding}
+
+// validEncoding returns whether the encoding is one of the supported encodings.
+func validEncoding(encoding string) bool {
+	for _, e := range encodingListString {
+		if encoding == e {
+			return true
+		}
+	}
+	return false
+}
+
+// Sink is a log destination created by a SinkFactory.
+type Sink interface {
+	zapcore.WriteSyncer
+	io.Closer
+}
+
+// EntrySink is implemented by sinks that need details of each log entry along with its
+// encoded form, such as to map the entry's level to a priority. Entries are written to
+// such sinks with WriteEntry rather than Write.
+type EntrySink interface {
+	Sink
+	WriteEntry(ent zapcore.Entry, p []byte) error
+}
+
+// SinkFactory creates a sink for a URL with a registered scheme.
+type SinkFactory func(u *url.URL) (Sink, error)
+
+var (
+	sinkFactories     = make(map[string]SinkFactory)
+	sinkFactoriesLock sync.RWMutex
+
+	// schemePattern matches valid URL schemes as defined by RFC 3986.
+	schemePattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*$`)
+)
+
+// RegisterSink registers a factory for log destinations addressed by URLs with the given
+// scheme, such as ring://main?size=100. Once registered, such URLs can be used anywhere a log
+// path is accepted, including OutputPath and the --log_path flag. Schemes are case-insensitive
+// and can only be registered once.
+func RegisterSink(scheme string, factory SinkFactory) error {
+	if !schemePattern.MatchString(scheme) {
+		return fmt.Errorf("invalid sink scheme '%s'", scheme)
+	}
+	if factory == nil {
+		return fmt.Errorf("no factory specified for sink scheme '%s'", scheme)
+	}
+
+	sinkFactoriesLock.Lock()
+	defer sinkFactoriesLock.Unlock()
+
+	scheme = strings.ToLower(scheme)
+	if _, ok := sinkFactories[scheme]; ok {
+		return fmt.Errorf("sink scheme '%s' is already registered", scheme)
+	}
+	sinkFactories[scheme] = factory
+
+	return nil
+}
+
+// openSink opens the log destination at the path. URLs with a scheme registered by
+// RegisterSink are opened by its factory, and everything else by zap.Open.
+func openSink(path string) (zapcore.WriteSyncer, func(), error) {
+	if u, err := url.Parse(path); err == nil && u.Scheme != "" {
+		sinkFactoriesLock.RLock()
+		factory, ok := sinkFactories[strings.ToLower(u.Scheme)]
+		sinkFactoriesLock.RUnlock()
+
+		if ok {
+			sink, err := factory(u)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to open sink '%s': %v", path, err)
+			}
+			return sink, func() { _ = sink.Close() }, nil
+		}
+	}
+
+	return zap.Open(path)
+}
+
+// SinkOptions describes an additional log output with its own encoding and minimum level.
+type SinkOptions struct {
+	// Path is where to write the log data. This can be a file system path, a URL with
+	// a scheme registered by RegisterSink or supported by zap.Open, as well as the
+	// special values stdout and stderr.
+	Path string `json:"path"`
+
+	// Rotate controls whether Path is a rotating log file, rotated based on the
+	// rotation settings of the enclosing Options.
+	Rotate bool `json:"rotate"`
+
+	// Encoding is the format of the log entries, one of console, json, pretty, logfmt,
+	// ecs and otel. This defaults to console.
+	Encoding string `json:"encoding"`
+
+	// Level is the minimum level of messages written to this output. Messages must
+	// also be enabled by the scope logging them. This defaults to debug, writing
+	// every message output by a scope.
+	Level string `json:"level"`
+
+	// Core, when set, receives the log entries in place of Path, for custom
+	// destinations. Encoding and Rotate are ignored.
+	Core zapcore.Core `json:"-"`
+}
+
+// validate checks the sink options, returning all problems found.
+func (so *SinkOptions) validate() []error {
+	var errs []error
+
+	if so.Core == nil {
+		if so.Path == "" {
+			errs = append(errs, errors.New("no path specified"))
+		} else if err := checkWritable(so.Path, so.Rotate); err != nil {
+			errs = append(errs, fmt.Errorf("invalid path: %v", err))
+		}
+
+		if so.Encoding != "" && !validEncoding(so.Encoding) {
+			errs = append(errs, fmt.Errorf("invalid encoding '%s', must be one of %s", so.Encoding, encodingListString))
+		}
+	}
+
+	if _, ok := stringToLevel[so.Level]; so.Level != "" && !ok {
+		errs = append(errs, fmt.Errorf("invalid level '%s', must be one of %s", so.Level, levelListString))
+	}
+
+	return errs
+}
+
+// newEncoder returns an encoder for the named encoding, which defaults to console. Pretty
+// output written to path is colored according to the options. The pretty, ecs and otel
+// encodings ignore the encoder config of the options.
+func newEncoder(encoding string, options *Options, path string) zapcore.Encoder {
+	encCfg, _ := options.encoderConfig()
+	switch encoding {
+	case JSONEncoding:
+		return zapcore.NewJSONEncoder(encCfg)
+	case PrettyEncoding:
+		return newPrettyEncoder(useColor(options.Color, path), options.PrettyTimestamps)
+	case LogfmtEncoding:
+		return newLogfmtEncoder(encCfg)
+	case ECSEncoding:
+		return newECSEncoder()
+	case OTelEncoding:
+		return newOTelEncoder()
+	}
+	return zapcore.NewConsoleEncoder(encCfg)
+}
+
+// openSinks returns a core for each sink described by the options, along with the
+// functions closing the sinks opened. Opened sinks are passed through wrap. If a sink
+// fails to open, the sinks opened before it are closed.
+func openSinks(options *Options, wrap func(zapcore.WriteSyncer) zapcore.WriteSyncer) ([]zapcore.Core, []func() error, error) {
+	cores := make([]zapcore.Core, 0, len(options.Sinks))
+	var closers []func() error
+	for i := range options.Sinks {
+		so := &options.Sinks[i]
+
+		level := DebugLevel
+		if so.Level != "" {
+			level = stringToLevel[so.Level]
+		}
+		enabler := zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
+			return toLevel[lvl] <= level
+		})
+
+		if so.Core != nil {
+			cores = append(cores, &leveledCore{Core: so.Core, enabler: enabler})
+			continue
+		}
+
+		var ws zapcore.WriteSyncer
+		var err error
+		if so.Rotate {
+			var rs Sink
+			if rs, err = newRotatingSink(so.Path, options); err == nil {
+				ws = rs
+				closers = append(closers, rs.Close)
+			}
+		} else {
+			var closeSink func()
+			if ws, closeSink, err = openSink(so.Path); err == nil {
+				closers = append(closers, func() error {
+					closeSink()
+					return nil
+				})
+			}
+		}
+		if err != nil {
+			_ = closeAll(closers)
+			return nil, nil, err
+		}
+
+		core := newCore(newEncoder(so.Encoding, options, so.Path), wrap(ws), enabler)
+		cores = append(cores, &leveledCore{Core: core, enabler: enabler})
+	}
+
+	return cores, closers, nil
+}
+
+// closeAll calls the closers in reverse order, returning all errors.
+func closeAll(closers []func() error) error {
+	var errs []error
+	for i := len(closers) - 1; i >= 0; i-- {
+		errs = append(errs, closers[i]())
+	}
+	return errors.Join(errs...)
+}
+
+// leveledCore only writes entries at the levels enabled by both the wrapped core and the
+// enabler, even when Write is called without checking first.
+type leveledCore struct {
+	zapcore.Core
+	enabler zapcore.LevelEnabler
+}
+
+func (c *leveledCore) Enabled(lvl zapcore.Level) bool {
+	return c.enabler.Enabled(lvl) && c.Core.Enabled(lvl)
+}
+
+func (c *leveledCore) With(fields []zapcore.Field) zapcore.Core {
+	return &leveledCore{Core: c.Core.With(fields), enabler: c.enabler}
+}
+
+func (c *leveledCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.enabler.Enabled(ent.Level) {
+		return c.Core.Check(ent, ce)
+	}
+	return ce
+}
+
+func (c *leveledCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if !c.Enabled(ent.Level) {
+		return nil
+	}
+	return c.Core.Write(ent, fields)
+}
+
+// newCore returns a core writing entries encoded by enc to ws. Sinks implementing EntrySink
+// receive the entries along with their encoded form.
+func newCore(enc zapcore.Encoder, ws zapcore.WriteSyncer, enabler zapcore.LevelEnabler) zapcore.Core {
+	if es, ok := ws.(EntrySink); ok {
+		return &entryCore{LevelEnabler: enabler, enc: enc, sink: es}
+	}
+	return zapcore.NewCore(enc, ws, enabler)
+}
+
+// entryCore writes entries to an EntrySink.
+type entryCore struct {
+	zapcore.LevelEnabler
+	enc  zapcore.Encoder
+	sink EntrySink
+}
+
+func (c *entryCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for i := range fields {
+		fields[i].AddTo(enc)
+	}
+	return &entryCore{LevelEnabler: c.LevelEnabler, enc: enc, sink: c.sink}
+}
+
+func (c *entryCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *entryCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	err = c.sink.WriteEntry(ent, buf.Bytes())
+	buf.Free()
+	if err != nil {
+		return err
+	}
+
+	if ent.Level > zapcore.ErrorLevel {
+		// since we may be crashing the program, sync the output
+		return c.Sync()
+	}
+	return nil
+}
+
+func (c *entryCore) Sync() error {
+	return c.sink.Sync()
+}
@@ -0,0 +1,269 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"bytes"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestSinks(t *testing.T) {
+	dir := t.TempDir()
+	jsonPath := filepath.Join(dir, "debug.json")
+	consolePath := filepath.Join(dir, "warn.log")
+	rotatePath := filepath.Join(dir, "rotate", "error.log")
+	observed, logs := observer.New(zapcore.DebugLevel)
+
+	lines, err := captureStdout(func() {
+		o := testOptions()
+		o.OutputLevel = "debug"
+		o.Sinks = []SinkOptions{
+			{Path: jsonPath, Encoding: JSONEncoding},
+			{Path: consolePath, Level: "warn"},
+			{Path: rotatePath, Rotate: true, Level: "error"},
+			{Core: observed, Level: "info"},
+		}
+		if err := Configure(o); err != nil {
+			t.Errorf("Got err '%v', expecting success", err)
+		}
+
+		Debug("debug")
+		Info("info")
+		Warn("warn")
+		Error("error")
+		zap.L().Warn("zap-warn")
+		_ = Sync()
+	})
+	if err != nil {
+		t.Fatalf("Got error '%v', expected success", err)
+	}
+	_ = Configure(testOptions())
+
+	cases := []struct {
+		name     string
+		lines    []string
+		patterns []string
+	}{
+		{
+			name:     "stdout",
+			lines:    lines,
+			patterns: []string{"\tdebug\tdebug$", "\tinfo\tinfo$", "\twarn\twarn$", "\terror\terror$", "\twarn\tzap-warn$", "^$"},
+		},
+		{
+			name:  "json",
+			lines: readLines(t, jsonPath),
+			patterns: []string{
+				`^\{"level":"debug",.*"msg":"debug"\}$`, `^\{"level":"info",.*"msg":"info"\}$`, `^\{"level":"warn",.*"msg":"warn"\}$`,
+				`^\{"level":"error",.*"msg":"error"\}$`, `^\{"level":"warn",.*"msg":"zap-warn"\}$`, "^$",
+			},
+		},
+		{
+			name:     "console",
+			lines:    readLines(t, consolePath),
+			patterns: []string{"\twarn\twarn$", "\terror\terror$", "\twarn\tzap-warn$", "^$"},
+		},
+		{
+			name:     "rotate",
+			lines:    readLines(t, rotatePath),
+			patterns: []string{"\terror\terror$", "^$"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if len(c.lines) != len(c.patterns) {
+				t.Fatalf("Got %d lines of output %q, expecting %d", len(c.lines), c.lines, len(c.patterns))
+			}
+			for i, pat := range c.patterns {
+				if match, _ := regexp.MatchString(pat, c.lines[i]); !match {
+					t.Errorf("Got '%s', expecting to match '%s'", c.lines[i], pat)
+				}
+			}
+		})
+	}
+
+	var messages []string
+	for _, e := range logs.All() {
+		messages = append(messages, e.Message)
+	}
+	if got := strings.Join(messages, ","); got != "info,warn,error,zap-warn" {
+		t.Errorf("Got observed messages %s, expecting info,warn,error,zap-warn", got)
+	}
+}
+
+func TestInvalidSinks(t *testing.T) {
+	o := testOptions()
+	o.Sinks = []SinkOptions{
+		{Encoding: "xml", Level: "loud"},
+		{Core: zapcore.NewNopCore()},
+	}
+
+	err := o.Validate()
+	if err == nil {
+		t.Fatal("Got success, expecting errors")
+	}
+
+	want := []string{
+		"invalid sink 0: no path specified",
+		"invalid sink 0: invalid encoding 'xml'",
+		"invalid sink 0: invalid level 'loud'",
+	}
+	lines := strings.Split(err.Error(), "\n")
+	if len(lines) != len(want) {
+		t.Fatalf("Got %d errors %q, expecting %d", len(lines), lines, len(want))
+	}
+	for i, w := range want {
+		if !strings.HasPrefix(lines[i], w) {
+			t.Errorf("Got error '%s', expecting prefix '%s'", lines[i], w)
+		}
+	}
+}
+
+// memorySink collects everything written to it, for testing custom sinks.
+type memorySink struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (m *memorySink) Sync() error { return nil }
+
+func (m *memorySink) Close() error {
+	m.closed = true
+	return nil
+}
+
+var (
+	// memorySinks are the sinks opened for memory:// URLs, keyed by host.
+	memorySinks        = make(map[string]*memorySink)
+	registerMemorySink sync.Once
+)
+
+// useMemorySinks registers the memory scheme, opening memory sinks.
+func useMemorySinks(t *testing.T) {
+	t.Helper()
+
+	registerMemorySink.Do(func() {
+		err := RegisterSink("Memory", func(u *url.URL) (Sink, error) {
+			if u.Host == "fail" {
+				return nil, errors.New("boom")
+			}
+			m := &memorySink{}
+			memorySinks[u.Host] = m
+			return m, nil
+		})
+		if err != nil {
+			t.Fatalf("Got error '%v', expected success", err)
+		}
+	})
+}
+
+func TestRegisterSink(t *testing.T) {
+	useMemorySinks(t)
+
+	o := testOptions()
+	o.OutputPath = "memory://main"
+	o.Sinks = []SinkOptions{{Path: "MEMORY://extra", Encoding: JSONEncoding}}
+	if err := Configure(o); err != nil {
+		t.Fatalf("Got err '%v', expecting success", err)
+	}
+	Info("custom")
+	_ = Sync()
+	_ = Configure(testOptions())
+
+	if got := memorySinks["main"].String(); !strings.HasSuffix(got, "\tinfo\tcustom\n") {
+		t.Errorf("Got %q, expecting the main sink to receive the message", got)
+	}
+	if got := memorySinks["extra"].String(); !strings.HasSuffix(got, `"msg":"custom"}`+"\n") {
+		t.Errorf("Got %q, expecting the extra sink to receive the message as JSON", got)
+	}
+
+	o.OutputPath = "memory://fail"
+	o.Sinks = nil
+	if err := Configure(o); err == nil || !strings.Contains(err.Error(), "failed to open sink 'memory://fail': boom") {
+		t.Errorf("Got %v, expecting the factory error", err)
+	}
+}
+
+func TestSinksClosed(t *testing.T) {
+	useMemorySinks(t)
+
+	o := testOptions()
+	o.OutputPath = "memory://closed_main"
+	o.Sinks = []SinkOptions{{Path: "memory://closed_extra"}}
+	if err := Configure(o); err != nil {
+		t.Fatalf("Got err '%v', expecting success", err)
+	}
+	main, extra := memorySinks["closed_main"], memorySinks["closed_extra"]
+	if main.closed || extra.closed {
+		t.Fatal("Got sinks closed, expecting them to be open while in use")
+	}
+
+	// configuring again closes the previous sinks
+	_ = Configure(testOptions())
+	if !main.closed || !extra.closed {
+		t.Errorf("Got sinks closed %v and %v, expecting both to be closed", main.closed, extra.closed)
+	}
+
+	// sinks opened before one failing to open are closed
+	o.Sinks = []SinkOptions{{Path: "memory://closed_extra"}, {Path: "memory://fail"}}
+	if err := Configure(o); err == nil {
+		t.Fatal("Got success, expecting the factory error")
+	}
+	if !memorySinks["closed_main"].closed || !memorySinks["closed_extra"].closed {
+		t.Error("Got sinks left open, expecting the sinks opened before the failure to be closed")
+	}
+}
+
+func TestRegisterSinkErrors(t *testing.T) {
+	factory := func(*url.URL) (Sink, error) { return &memorySink{}, nil }
+	_ = RegisterSink("dup", factory)
+
+	cases := []struct {
+		scheme  string
+		factory SinkFactory
+		err     string
+	}{
+		{"", factory, "invalid sink scheme ''"},
+		{"1abc", factory, "invalid sink scheme '1abc'"},
+		{"a_b", factory, "invalid sink scheme 'a_b'"},
+		{"nofactory", nil, "no factory specified for sink scheme 'nofactory'"},
+		{"DUP", factory, "sink scheme 'dup' is already registered"},
+	}
+	for _, c := range cases {
+		if err := RegisterSink(c.scheme, c.factory); err == nil || err.Error() != c.err {
+			t.Errorf("Got %v, expecting '%s'", err, c.err)
+		}
+	}
+}
+
+func readLines(t *testing.T, path string) []string {
+	t.Helper()
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Got error '%v', expected success", err)
+	}
+	return strings.Split(string(content), "\n")
+}
@@ -0,0 +1,138 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Size is a number of bytes, so that a Size set in Go is in bytes, such as
+// 100 * Mebibyte. It is parsed from a number followed by a unit, such as 100Mi or 1G,
+// where binary units are suffixed with i. The unit is required for every size but 0.
+type Size int64
+
+// Binary size units.
+const (
+	Byte     Size = 1
+	Kibibyte      = 1024 * Byte
+	Mebibyte      = 1024 * Kibibyte
+	Gibibyte      = 1024 * Mebibyte
+	Tebibyte      = 1024 * Gibibyte
+)
+
+// sizeUnits maps lower-cased unit suffixes to their number of bytes.
+var sizeUnits = map[string]Size{
+	"b":   Byte,
+	"k":   1000,
+	"kb":  1000,
+	"ki":  Kibibyte,
+	"kib": Kibibyte,
+	"m":   1000 * 1000,
+	"mb":  1000 * 1000,
+	"mi":  Mebibyte,
+	"mib": Mebibyte,
+	"g":   1000 * 1000 * 1000,
+	"gb":  1000 * 1000 * 1000,
+	"gi":  Gibibyte,
+	"gib": Gibibyte,
+	"t":   1000 * 1000 * 1000 * 1000,
+	"tb":  1000 * 1000 * 1000 * 1000,
+	"ti":  Tebibyte,
+	"tib": Tebibyte,
+}
+
+// ParseSize parses a size such as 100Mi or 1G. Units are case-insensitive, and can only
+// be omitted for 0.
+func ParseSize(s string) (Size, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool { return r < '0' || r > '9' })
+	if i < 0 {
+		i = len(s)
+	}
+
+	n, err := strconv.ParseInt(s[:i], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size '%s', must be a non-negative number followed by a unit such as Mi or G", s)
+	}
+
+	suffix := strings.TrimSpace(s[i:])
+	if suffix == "" {
+		if n != 0 {
+			return 0, fmt.Errorf("invalid size '%s', a unit such as Mi or G is required", s)
+		}
+		return 0, nil
+	}
+	unit, ok := sizeUnits[strings.ToLower(suffix)]
+	if !ok {
+		return 0, fmt.Errorf("invalid size '%s', unknown unit '%s'", s, suffix)
+	}
+
+	if n > math.MaxInt64/int64(unit) {
+		return 0, fmt.Errorf("invalid size '%s', too large", s)
+	}
+	return Size(n) * unit, nil
+}
+
+// String returns the size in the largest binary unit it is a whole multiple of, such
+// as 100Mi, or in bytes, such as 1500B.
+func (s Size) String() string {
+	for _, u := range []struct {
+		size   Size
+		suffix string
+	}{{Tebibyte, "Ti"}, {Gibibyte, "Gi"}, {Mebibyte, "Mi"}, {Kibibyte, "Ki"}} {
+		if s != 0 && s%u.size == 0 {
+			return strconv.FormatInt(int64(s/u.size), 10) + u.suffix
+		}
+	}
+	return strconv.FormatInt(int64(s), 10) + "B"
+}
+
+// Set parses the size, so that sizes can be used as flags.
+func (s *Size) Set(value string) error {
+	size, err := ParseSize(value)
+	if err != nil {
+		return err
+	}
+	*s = size
+	return nil
+}
+
+// Type returns the type name shown in flag usages.
+func (s *Size) Type() string {
+	return "size"
+}
+
+// MarshalJSON encodes the size as a string, such as "100Mi".
+func (s Size) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// UnmarshalJSON decodes a size from a string, such as "100Mi". Numbers are rejected as
+// their unit is ambiguous, except for 0.
+func (s *Size) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		var n json.Number
+		if json.Unmarshal(data, &n) != nil {
+			return fmt.Errorf("invalid size %s, must be a string such as \"100Mi\"", data)
+		}
+		value = n.String()
+	}
+	return s.Set(value)
+}
@@ -0,0 +1,99 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParseSize(t *testing.T) {
+	cases := []struct {
+		input  string
+		size   Size
+		output string
+	}{
+		{"0", 0, "0B"},
+		{"100Mi", 100 * Mebibyte, "100Mi"},
+		{"100MiB", 100 * Mebibyte, "100Mi"},
+		{"1G", 1000 * 1000 * 1000, "1000000000B"},
+		{"1gb", 1000 * 1000 * 1000, "1000000000B"},
+		{"2048Ki", 2 * Mebibyte, "2Mi"},
+		{"1500B", 1500, "1500B"},
+		{"1 Ti", Tebibyte, "1Ti"},
+		{"10k", 10000, "10000B"},
+	}
+	for _, c := range cases {
+		size, err := ParseSize(c.input)
+		if err != nil {
+			t.Errorf("Got error '%v' for %s, expected success", err, c.input)
+			continue
+		}
+		if size != c.size {
+			t.Errorf("Got %d for %s, expected %d", size, c.input, c.size)
+		}
+		if size.String() != c.output {
+			t.Errorf("Got %s for %s, expected %s", size, c.input, c.output)
+		}
+
+		// the string form parses back to the same size
+		if again, err := ParseSize(size.String()); err != nil || again != size {
+			t.Errorf("Got %d, %v parsing %s, expected %d", again, err, size, size)
+		}
+	}
+
+	errs := []struct {
+		input string
+		err   string
+	}{
+		{"", "invalid size ''"},
+		{"-1Mi", "invalid size '-1Mi'"},
+		{"100", "invalid size '100', a unit such as Mi or G is required"},
+		{"1.5G", "invalid size '1.5G', unknown unit '.5G'"},
+		{"10X", "invalid size '10X', unknown unit 'X'"},
+		{"99999999Ti", "invalid size '99999999Ti', too large"},
+	}
+	for _, c := range errs {
+		if _, err := ParseSize(c.input); err == nil || !strings.HasPrefix(err.Error(), c.err) {
+			t.Errorf("Got %v for '%s', expecting '%s'", err, c.input, c.err)
+		}
+	}
+}
+
+func TestSizeJSON(t *testing.T) {
+	var v struct {
+		A Size `json:"a"`
+		B Size `json:"b"`
+	}
+	if err := json.Unmarshal([]byte(`{"a": "1Gi", "b": 0}`), &v); err != nil {
+		t.Fatalf("Got error '%v', expected success", err)
+	}
+	if v.A != Gibibyte || v.B != 0 {
+		t.Errorf("Got %d and %d, expected %d and 0", v.A, v.B, Gibibyte)
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil || string(data) != `{"a":"1Gi","b":"0B"}` {
+		t.Errorf("Got %s, %v, expected the sizes as strings", data, err)
+	}
+
+	if err := json.Unmarshal([]byte(`{"a": true}`), &v); err == nil || !strings.Contains(err.Error(), "must be a string") {
+		t.Errorf("Got %v, expecting an invalid size error", err)
+	}
+	if err := json.Unmarshal([]byte(`{"a": 5}`), &v); err == nil || !strings.Contains(err.Error(), "a unit such as Mi or G is required") {
+		t.Errorf("Got %v, expecting numbers without a unit to be rejected", err)
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// slogHandler should implement the slog.Handler interface.
+var _ slog.Handler = &slogHandler{}
+
+// NewSlogHandler returns a slog.Handler that writes records through the given scope, so that
+// they honor the scope's levels and the configured outputs and encoding. Attributes and groups
+// become structured fields, and values stored in the context are attached as with InfoCtx.
+//
+// Records below slog.LevelInfo are logged at debug level, and records at or above
+// slog.LevelError at error level. Records never cause the process to exit.
+func NewSlogHandler(s *Scope) slog.Handler {
+	return &slogHandler{logger: s.logger}
+}
+
+type slogHandler struct {
+	logger *logger
+	// groups are the groups opened with WithGroup that don't contain any attributes yet.
+	groups []string
+}
+
+// Enabled reports whether the scope outputs records at the given level.
+func (h *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.logger.GetOutputLevel() >= toLevel[slogToZapLevel(level)]
+}
+
+// Handle writes the record through the scope.
+func (h *slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	level := slogToZapLevel(r.Level)
+	if h.logger.GetOutputLevel() < toLevel[level] {
+		return nil
+	}
+
+	e := zapcore.Entry{
+		Message: r.Message,
+		Level:   level,
+		Time:    r.Time,
+	}
+
+	if h.logger.GetLogCallers() && r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		e.Caller = zapcore.NewEntryCaller(frame.PC, frame.File, frame.Line, true)
+	}
+
+	fields := make([]zapcore.Field, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		fields = appendSlogAttr(fields, a)
+		return true
+	})
+	if len(fields) > 0 {
+		fields = append(h.openGroups(), fields...)
+	}
+
+	h.logger.write(e, append(fieldsFromContext(ctx, nil), fields...))
+	return nil
+}
+
+// WithAttrs returns a handler that attaches the attributes to every record it handles.
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var fields []zapcore.Field
+	for _, a := range attrs {
+		fields = appendSlogAttr(fields, a)
+	}
+	if len(fields) == 0 {
+		return h
+	}
+
+	keysAndValues := make([]any, 0, len(h.groups)+len(fields))
+	for _, f := range append(h.openGroups(), fields...) {
+		keysAndValues = append(keysAndValues, f)
+	}
+
+	return &slogHandler{logger: h.logger.with(keysAndValues)}
+}
+
+// WithGroup returns a handler that nests all further attributes in the named group.
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	groups := make([]string, 0, len(h.groups)+1)
+	groups = append(groups, h.groups...)
+	return &slogHandler{
+		logger: h.logger,
+		groups: append(groups, name),
+	}
+}
+
+// openGroups returns the namespace fields for the groups that don't contain any attributes yet.
+func (h *slogHandler) openGroups() []zapcore.Field {
+	fields := make([]zapcore.Field, 0, len(h.groups))
+	for _, g := range h.groups {
+		fields = append(fields, zap.Namespace(g))
+	}
+	return fields
+}
+
+// slogToZapLevel maps a slog level onto the closest zap level supported by this package.
+func slogToZapLevel(level slog.Level) zapcore.Level {
+	switch {
+	case level < slog.LevelInfo:
+		return zapcore.DebugLevel
+	case level < slog.LevelWarn:
+		return zapcore.InfoLevel
+	case level < slog.LevelError:
+		return zapcore.WarnLevel
+	}
+	return zapcore.ErrorLevel
+}
+
+// appendSlogAttr converts the attribute into zap fields, following the slog.Handler rules
+// for empty attributes and groups.
+func appendSlogAttr(fields []zapcore.Field, a slog.Attr) []zapcore.Field {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+
+	v := a.Value
+	switch v.Kind() {
+	case slog.KindGroup:
+		attrs := v.Group()
+		if len(attrs) == 0 {
+			return fields
+		}
+		if a.Key == "" {
+			for _, ga := range attrs {
+				fields = appendSlogAttr(fields, ga)
+			}
+			return fields
+		}
+		return append(fields, zap.Object(a.Key, slogGroup(attrs)))
+	case slog.KindString:
+		return append(fields, zap.String(a.Key, v.String()))
+	case slog.KindInt64:
+		return append(fields, zap.Int64(a.Key, v.Int64()))
+	case slog.KindUint64:
+		return append(fields, zap.Uint64(a.Key, v.Uint64()))
+	case slog.KindFloat64:
+		return append(fields, zap.Float64(a.Key, v.Float64()))
+	case slog.KindBool:
+		return append(fields, zap.Bool(a.Key, v.Bool()))
+	case slog.KindDuration:
+		return append(fields, zap.Duration(a.Key, v.Duration()))
+	case slog.KindTime:
+		return append(fields, zap.Time(a.Key, v.Time()))
+	}
+
+	if err, ok := v.Any().(error); ok {
+		return append(fields, zap.NamedError(a.Key, err))
+	}
+	return append(fields, zap.Any(a.Key, v.Any()))
+}
+
+// slogGroup marshals the attributes of a slog group as a nested object.
+type slogGroup []slog.Attr
+
+func (g slogGroup) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for _, a := range g {
+		for _, f := range appendSlogAttr(nil, a) {
+			f.AddTo(enc)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/slogtest"
+)
+
+func TestSlogHandler(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "slog.log")
+	o := testOptions()
+	o.OutputPath = path
+	o.JSONEncoding = true
+	o.OutputLevel = "debug"
+	if err := Configure(o); err != nil {
+		t.Fatalf("Got err '%v', expecting success", err)
+	}
+	t.Cleanup(func() { _ = Configure(testOptions()) })
+
+	s := RegisterScope("slog", "")
+	read := 0
+	slogtest.Run(t, func(*testing.T) slog.Handler {
+		return NewSlogHandler(s)
+	}, func(t *testing.T) map[string]any {
+		_ = Sync()
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Got error '%v', expected success", err)
+		}
+		line := strings.TrimSpace(string(content[read:]))
+		read = len(content)
+
+		m := make(map[string]any)
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			t.Fatalf("Got error '%v' parsing '%s', expected success", err, line)
+		}
+		return m
+	})
+}
+
+func TestSlogLevels(t *testing.T) {
+	s := RegisterScope("slog", "")
+	h := NewSlogHandler(s)
+	s.SetOutputLevel(WarnLevel)
+
+	cases := []struct {
+		level   slog.Level
+		enabled bool
+	}{
+		{slog.LevelDebug, false},
+		{slog.LevelInfo, false},
+		{slog.LevelWarn - 1, false},
+		{slog.LevelWarn, true},
+		{slog.LevelError, true},
+		{slog.LevelError + 4, true},
+	}
+	for _, c := range cases {
+		if enabled := h.Enabled(context.Background(), c.level); enabled != c.enabled {
+			t.Errorf("Got enabled=%v for %v, expecting %v", enabled, c.level, c.enabled)
+		}
+	}
+}
+
+func TestCaptureSlog(t *testing.T) {
+	old := slog.Default()
+	defer slog.SetDefault(old)
+
+	lines, err := captureStdout(func() {
+		o := testOptions()
+		o.CaptureSlog = true
+		o.LogCaller = true
+		if err := Configure(o); err != nil {
+			t.Errorf("Got err '%v', expecting success", err)
+		}
+
+		slog.Info("slog-info", "stack", "demo")
+		slog.Debug("slog-debug")
+		slog.Default().WithGroup("g").Warn("slog-warn", "a", 1)
+		_ = Sync()
+	})
+	if err != nil {
+		t.Fatalf("Got error '%v', expected success", err)
+	}
+
+	patterns := []string{
+		`\tinfo\tlog/slog_test.go:.*\tslog-info\t\{"stack": "demo"\}$`,
+		`\twarn\tlog/slog_test.go:.*\tslog-warn\t\{"g": \{"a": 1\}\}$`,
+		"",
+	}
+	if len(lines) != len(patterns) {
+		t.Fatalf("Got %d lines of output %v, expecting %d", len(lines), lines, len(patterns))
+	}
+	for i, pat := range patterns {
+		if match, _ := regexp.MatchString(pat, lines[i]); !match {
+			t.Errorf("Got '%s', expecting to match '%s'", lines[i], pat)
+		}
+	}
+}
+
+func TestCaptureSlogRestore(t *testing.T) {
+	old := slog.Default()
+	defer slog.SetDefault(old)
+	defer func() { _ = Configure(DefaultOptions()) }()
+
+	o := testOptions()
+	o.CaptureSlog = true
+	if err := Configure(o); err != nil {
+		t.Fatalf("Got err '%v', expecting success", err)
+	}
+	// configuring again while capturing must not lose the original default
+	if err := Configure(o); err != nil {
+		t.Fatalf("Got err '%v', expecting success", err)
+	}
+	if _, ok := slog.Default().Handler().(*slogHandler); !ok {
+		t.Fatalf("Got handler %T, expecting slog output to be captured", slog.Default().Handler())
+	}
+
+	if err := Configure(testOptions()); err != nil {
+		t.Fatalf("Got err '%v', expecting success", err)
+	}
+	if slog.Default() != old {
+		t.Errorf("Got default logger with handler %T, expecting the previous default", slog.Default().Handler())
+	}
+
+	// a default replaced by the application after capturing is left alone
+	if err := Configure(o); err != nil {
+		t.Fatalf("Got err '%v', expecting success", err)
+	}
+	custom := slog.New(slog.NewTextHandler(io.Discard, nil))
+	slog.SetDefault(custom)
+	if err := Configure(testOptions()); err != nil {
+		t.Fatalf("Got err '%v', expecting success", err)
+	}
+	if slog.Default() != custom {
+		t.Errorf("Got default logger with handler %T, expecting the application's logger", slog.Default().Handler())
+	}
+}
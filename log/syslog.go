@@ -0,0 +1,206 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// SyslogScheme is the URL scheme of the built-in syslog sink. The sink sends
+	// RFC 5424 messages to a syslog daemon:
+	//
+	//	syslog://                           the local daemon at /dev/log
+	//	syslog:///path/to/socket            a local daemon at a unix datagram socket
+	//	syslog://host:514                   a remote daemon over UDP
+	//	syslog://host:514?network=tcp       a remote daemon over TCP
+	//
+	// The network query parameter can be one of udp, tcp, unixgram and unix. Messages
+	// over stream networks are framed using octet counting. The optional tag parameter
+	// sets the APP-NAME, which defaults to the program name, and the optional facility
+	// parameter sets the facility, such as daemon or local0, which defaults to user.
+	// The scope of an entry is used as its MSGID.
+	SyslogScheme = "syslog"
+
+	defaultSyslogPath = "/dev/log"
+)
+
+var syslogFacilities = map[string]int{
+	"kern":     0,
+	"user":     1,
+	"mail":     2,
+	"daemon":   3,
+	"auth":     4,
+	"syslog":   5,
+	"lpr":      6,
+	"news":     7,
+	"uucp":     8,
+	"cron":     9,
+	"authpriv": 10,
+	"ftp":      11,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+// levelToSyslogSeverity maps levels to syslog severities. Fatal entries are critical
+// conditions, while emergency and alert are left for system-wide problems.
+var levelToSyslogSeverity = map[zapcore.Level]int{
+	zapcore.DebugLevel:  7,
+	zapcore.InfoLevel:   6,
+	zapcore.WarnLevel:   4,
+	zapcore.ErrorLevel:  3,
+	zapcore.DPanicLevel: 2,
+	zapcore.PanicLevel:  2,
+	zapcore.FatalLevel:  2,
+}
+
+// syslogTimeFormat is the RFC 5424 timestamp format, limited to microsecond precision.
+const syslogTimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+
+// syslogSink should implement the EntrySink interface.
+var _ EntrySink = &syslogSink{}
+
+func init() {
+	_ = RegisterSink(SyslogScheme, newSyslogSink)
+}
+
+type syslogSink struct {
+	network  string
+	address  string
+	facility int
+	hostname string
+	tag      string
+	pid      int
+
+	conn *redialConn
+}
+
+func newSyslogSink(u *url.URL) (Sink, error) {
+	q := u.Query()
+	s := &syslogSink{
+		network:  q.Get("network"),
+		address:  u.Host,
+		facility: syslogFacilities["user"],
+		tag:      q.Get("tag"),
+		pid:      os.Getpid(),
+	}
+
+	if s.address == "" {
+		s.address = u.Path
+		if s.address == "" {
+			s.address = defaultSyslogPath
+		}
+		if s.network == "" {
+			s.network = "unixgram"
+		}
+	} else if s.network == "" {
+		s.network = "udp"
+	}
+
+	switch s.network {
+	case "udp", "tcp", "unixgram", "unix":
+	default:
+		return nil, fmt.Errorf("invalid syslog network '%s', must be one of [udp tcp unixgram unix]", s.network)
+	}
+
+	if f := q.Get("facility"); f != "" {
+		facility, ok := syslogFacilities[f]
+		if !ok {
+			return nil, fmt.Errorf("invalid syslog facility '%s'", f)
+		}
+		s.facility = facility
+	}
+
+	if s.tag == "" {
+		s.tag = filepath.Base(os.Args[0])
+	}
+
+	s.hostname, _ = os.Hostname()
+	if s.hostname == "" {
+		s.hostname = "-"
+	}
+
+	conn, err := dialRedialConn("syslog", s.network, s.address)
+	if err != nil {
+		return nil, err
+	}
+	s.conn = conn
+	return s, nil
+}
+
+// WriteEntry sends the encoded entry as a syslog message with the entry's severity.
+func (s *syslogSink) WriteEntry(ent zapcore.Entry, p []byte) error {
+	_, err := s.conn.Write(s.format(ent, p))
+	return err
+}
+
+// format returns the RFC 5424 message for the encoded entry, framed for the network.
+func (s *syslogSink) format(ent zapcore.Entry, p []byte) []byte {
+	severity, ok := levelToSyslogSeverity[ent.Level]
+	if !ok {
+		severity = levelToSyslogSeverity[zapcore.InfoLevel]
+	}
+
+	timestamp := "-"
+	if !ent.Time.IsZero() {
+		timestamp = ent.Time.Format(syslogTimeFormat)
+	}
+
+	msgID := ent.LoggerName
+	if msgID == "" {
+		msgID = "-"
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "<%d>1 %s %s %s %d %s - ", s.facility*8+severity, timestamp, s.hostname, s.tag, s.pid, msgID)
+	buf.Write(bytes.TrimRight(p, "\n"))
+
+	if s.network == "tcp" || s.network == "unix" {
+		return append([]byte(strconv.Itoa(buf.Len())+" "), buf.Bytes()...)
+	}
+	return buf.Bytes()
+}
+
+// Write sends p as a syslog message at info severity.
+func (s *syslogSink) Write(p []byte) (int, error) {
+	if err := s.WriteEntry(zapcore.Entry{Level: zapcore.InfoLevel, Time: time.Now()}, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Sync does nothing, as messages are sent as they are written.
+func (s *syslogSink) Sync() error {
+	return nil
+}
+
+// Close closes the connection to the syslog daemon.
+func (s *syslogSink) Close() error {
+	return s.conn.Close()
+}
@@ -0,0 +1,135 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !windows
+
+package log
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const syslogHeaderPattern = `\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}\.\d{6}(Z|[+-]\d{2}:\d{2}) \S+ `
+
+func TestSyslogSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "syslog.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("Got error '%v', expected success", err)
+	}
+	defer conn.Close()
+
+	o := testOptions()
+	o.Sinks = []SinkOptions{{Path: "syslog://" + path + "?tag=app&facility=local0"}}
+	if err := Configure(o); err != nil {
+		t.Fatalf("Got err '%v', expecting success", err)
+	}
+	defer func() { _ = Configure(testOptions()) }()
+
+	_, _ = captureStdout(func() {
+		Warn("warning")
+		RegisterScope("syslog", "").Error("failure")
+	})
+
+	patterns := []string{
+		`^<132>1 ` + syslogHeaderPattern + `app \d+ - - ` + timePattern + `\twarn\twarning$`,
+		`^<131>1 ` + syslogHeaderPattern + `app \d+ syslog - ` + timePattern + `\terror\tsyslog\tfailure$`,
+	}
+	buf := make([]byte, 4096)
+	for _, pattern := range patterns {
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatalf("Got error '%v', expected a message", err)
+		}
+		if !regexp.MustCompile(pattern).Match(buf[:n]) {
+			t.Errorf("Got %q, expected to match %s", buf[:n], pattern)
+		}
+	}
+}
+
+func TestSyslogSinkTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Got error '%v', expected success", err)
+	}
+	defer l.Close()
+
+	o := testOptions()
+	o.Sinks = []SinkOptions{{Path: "syslog://" + l.Addr().String() + "?network=tcp&tag=app", Encoding: JSONEncoding}}
+	if err := Configure(o); err != nil {
+		t.Fatalf("Got err '%v', expecting success", err)
+	}
+	defer func() { _ = Configure(testOptions()) }()
+
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatalf("Got error '%v', expected success", err)
+	}
+	defer conn.Close()
+
+	_, _ = captureStdout(func() {
+		Info("first")
+		Info("second")
+	})
+
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(conn)
+	for _, msg := range []string{"first", "second"} {
+		prefix, err := r.ReadString(' ')
+		if err != nil {
+			t.Fatalf("Got error '%v', expected an octet-counted frame", err)
+		}
+		length, err := strconv.Atoi(strings.TrimSuffix(prefix, " "))
+		if err != nil {
+			t.Fatalf("Got error '%v', expected the frame length", err)
+		}
+		frame := make([]byte, length)
+		if _, err := io.ReadFull(r, frame); err != nil {
+			t.Fatalf("Got error '%v', expected the frame", err)
+		}
+		got := string(frame)
+		pattern := `^<14>1 ` + syslogHeaderPattern + `app \d+ - - \{"level":"info","time":"[^"]+","msg":"` + msg + `"\}$`
+		if !regexp.MustCompile(pattern).MatchString(got) {
+			t.Errorf("Got %q, expected to match %s", got, pattern)
+		}
+	}
+}
+
+func TestInvalidSyslogSink(t *testing.T) {
+	cases := []struct {
+		path string
+		err  string
+	}{
+		{"syslog://localhost:514?network=sctp", "invalid syslog network 'sctp'"},
+		{"syslog://localhost:514?facility=nope", "invalid syslog facility 'nope'"},
+		{"syslog://" + filepath.Join(t.TempDir(), "missing.sock"), "failed to connect to syslog at unixgram"},
+	}
+	for _, c := range cases {
+		o := testOptions()
+		o.Sinks = []SinkOptions{{Path: c.path}}
+		if err := Configure(o); err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("Got %v for %s, expecting '%s'", err, c.path, c.err)
+		}
+	}
+	_ = Configure(testOptions())
+}